@@ -1,32 +1,363 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"log"
 	"os"
 
+	"github.com/samsarahq/go/oops"
+
 	"github.com/ecshreve/jcgo/pkg/parser"
 )
 
+const (
+	// exitError is the exit code when the conversion fails.
+	exitError = 1
+	// exitUsage is the exit code when the command line arguments are invalid.
+	exitUsage = 2
+)
+
+// usage is printed before the flag defaults when the command line arguments
+// are invalid, or when the -h flag is given.
+const usage = `usage: jcgo [flags] [infile] [outfile]
+
+Converts the JSON in infile to CSV and writes it to outfile. Use - for infile to
+read from stdin, and - for outfile to write to stdout. With no infile, stdin is
+read if something is piped to it. Output goes to stdout by default when reading
+from stdin, and to a generated file name otherwise. With -normalize, outfile is
+a directory and defaults to a generated name.
+
+flags:
+`
+
+// usageError is returned by parseArgs when the command line arguments are
+// invalid.
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return e.msg
+}
+
+// newUsageError returns a usageError with the given formatted message.
+func newUsageError(format string, args ...interface{}) error {
+	return usageError{fmt.Sprintf(format, args...)}
+}
+
+// patternList is a flag that can be given more than once, collecting the
+// path patterns for -include or -exclude.
+type patternList []string
+
+func (l *patternList) String() string {
+	return fmt.Sprint(*l)
+}
+
+func (l *patternList) Set(pattern string) error {
+	*l = append(*l, pattern)
+	return nil
+}
+
+// parsePatterns returns the PathPatterns for the patterns given with the flag
+// of the given name, or a usageError if any of them is malformed.
+func parsePatterns(name string, patterns patternList) ([]*parser.PathPattern, error) {
+	var ret []*parser.PathPattern
+	for _, pattern := range patterns {
+		p, err := parser.ParsePathPattern(pattern)
+		if err != nil {
+			return nil, newUsageError("invalid -%s: %s", name, pattern)
+		}
+		ret = append(ret, p)
+	}
+	return ret, nil
+}
+
+// args holds the result of parsing the command line arguments.
+type args struct {
+	infilePath   string
+	outfilePath  *string
+	outputFormat parser.OutputFormat
+	overwrite    bool
+	normalize    bool
+	opts         []parser.Option
+}
+
 func main() {
-	if len(os.Args) == 1 {
-		log.Fatal("please provide an input file")
+	os.Exit(run(os.Args[1:], os.Stderr))
+}
+
+// run converts JSON to CSV as described by the given command line arguments,
+// and returns the exit code. Errors and usage information are written to the
+// given io.Writer.
+func run(argv []string, stderr io.Writer) int {
+	logger := log.New(stderr, "", log.LstdFlags)
+
+	flags := flag.NewFlagSet("jcgo", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+
+	a, err := parseArgs(flags, argv)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	var uerr usageError
+	if errors.As(err, &uerr) {
+		fmt.Fprintf(stderr, "jcgo: %s\n\n", uerr.msg)
+		flags.Usage()
+		return exitUsage
+	}
+	if err != nil {
+		// The flag package has already printed the error and the usage.
+		return exitUsage
+	}
+
+	// Check the output file before doing any work.
+	if !a.overwrite && a.outfilePath != nil && *a.outfilePath != parser.StdioPath {
+		if _, err := os.Stat(*a.outfilePath); err == nil {
+			logger.Printf("output file already exists: %s, use -overwrite to replace it", *a.outfilePath)
+			return exitError
+		}
+	}
+
+	if a.normalize {
+		paths, err := parser.ConvertJSONFileTables(a.infilePath, a.outfilePath, a.opts...)
+		if err != nil {
+			logger.Printf("error converting json file: %v", err)
+			return exitError
+		}
+
+		for _, path := range paths {
+			logger.Printf("generated %s file: %v\n", a.outputFormat, path)
+		}
+		return 0
+	}
+
+	outfile, err := parser.ConvertJSONFile(&a.infilePath, a.outfilePath, a.opts...)
+	if err != nil {
+		logger.Printf("error converting json file: %v", err)
+		return exitError
+	}
+
+	if outfile != os.Stdout {
+		logger.Printf("generated %s file: %v\n", a.outputFormat, outfile.Name())
 	}
+	return 0
+}
 
-	if len(os.Args) > 3 {
-		log.Fatal("too many command line arguments")
+// parseArgs parses the given command line arguments with the given FlagSet,
+// and returns the resulting args, or a usageError if they're invalid.
+func parseArgs(flags *flag.FlagSet, argv []string) (*args, error) {
+	truncate := flags.Bool("truncate", true, "remove the longest common prefix from the column headers")
+	truncateStrategy := flags.String("truncate-strategy", "prefix", "how -truncate shortens the column headers: prefix removes the longest common prefix, suffix keeps the shortest unique suffix")
+	duplicateHeaders := flags.String("duplicate-headers", "error", "what happens when two columns have the same header: error, suffix adds _2 and _3, or path uses the JSONPath of each column")
+	delimiter := flags.String("delimiter", "", "field delimiter, a single character or \\t (default from -output-format)")
+	nullToken := flags.String("null", "", "value written for JSON null values")
+	numberFormat := flags.String("number-format", "original", "how numbers are written: original, fixed, or scientific")
+	numberPrecision := flags.Int("number-precision", -1, "digits after the decimal point for fixed and scientific numbers, -1 for as many as needed")
+	arrayMode := flags.String("arrays", "rows", "how the elements of nested arrays are written: rows, or columns like events_0_id")
+	maxArrayElements := flags.Int("max-array-elements", 0, "most elements of each array written as columns with -arrays columns, 0 for no limit")
+	siblings := flags.String("siblings", "repeat", "how sibling arrays are combined: repeat the last row of shorter ones, or zip them element by element")
+	zipLengths := flags.String("zip-lengths", "pad", "how sibling arrays of different lengths are zipped: pad, truncate, or error")
+	scalarArrays := flags.String("scalar-arrays", "expand", "how arrays of scalar values are written: expand like other arrays, join into one cell, or json")
+	scalarSeparator := flags.String("scalar-separator", "|", "placed between the elements of a scalar array with -scalar-arrays join")
+	maxDepth := flags.Int("max-depth", 0, "most keys in a column header, deeper values are written as JSON, 0 for no limit")
+	maxRows := flags.Int("max-rows", 0, "most data rows in the output, the conversion fails if there would be more, 0 for no limit")
+	maxColumns := flags.Int("max-columns", 0, "most columns in the output, 0 for no limit")
+	maxCells := flags.Int("max-cells", 0, "most cells in the data rows of the output, 0 for no limit")
+	emptyPolicy := flags.String("empty", "blank", "how empty arrays and objects are written: blank, literal, or drop")
+	separator := flags.String("separator", "_", "placed between nested keys to build the column headers")
+	headerStyle := flags.String("header-style", "plain", "how keys are joined into headers: plain, escaped, or pointer")
+	keyOrder := flags.String("key-order", "alphabetical", "order of the columns for the keys of a map: alphabetical or document")
+	inputFormat := flags.String("input-format", "auto", "format of the input: auto, json, or ndjson")
+	outputFormat := flags.String("output-format", "csv", "format of the output: csv or tsv")
+	overwrite := flags.Bool("overwrite", true, "replace the output file if it already exists")
+	normalize := flags.Bool("normalize", false, "write a linked table for each array path to files in the outfile directory")
+	stream := flags.Bool("stream", false, "convert the input one record at a time without loading it all into memory")
+	streamPath := flags.String("stream-path", "", "JSON Pointer to the array of records to stream, e.g. /data/items")
+	mappingPath := flags.String("mapping", "", "YAML or JSON file listing the columns to write, with their sources, names and defaults")
+	var include, exclude patternList
+	flags.Var(&include, "include", "write only the columns whose header matches a glob, or a regexp starting with re:, can be repeated")
+	flags.Var(&exclude, "exclude", "leave out the columns whose header matches a glob, or a regexp starting with re:, can be repeated")
+	root := flags.String("root", "", "JSON Pointer or JSONPath of the part of the input to convert, e.g. /data/items or $.pages[*].items")
+
+	if err := flags.Parse(argv); err != nil {
+		return nil, err
 	}
 
-	infilePath := &os.Args[1]
+	if flags.NArg() > 2 {
+		return nil, newUsageError("too many command line arguments")
+	}
 
-	var outfilePath *string
-	if len(os.Args) == 3 {
-		outfilePath = &os.Args[2]
+	inFormat, err := parser.ParseInputFormat(*inputFormat)
+	if err != nil {
+		return nil, newUsageError("invalid -input-format: %s", *inputFormat)
 	}
 
-	outfile, err := parser.ConvertJSONFile(infilePath, outfilePath)
+	outFormat, err := parser.ParseOutputFormat(*outputFormat)
 	if err != nil {
-		log.Fatalf("error converting json file: %v", err)
+		return nil, newUsageError("invalid -output-format: %s", *outputFormat)
+	}
+
+	a := &args{
+		outputFormat: outFormat,
+		overwrite:    *overwrite,
+		normalize:    *normalize,
+		opts: []parser.Option{
+			parser.WithTruncateHeaders(*truncate),
+			parser.WithInputFormat(inFormat),
+			parser.WithOutputFormat(outFormat),
+			parser.WithNullToken(*nullToken),
+			parser.WithStreaming(*stream),
+			parser.WithStreamPath(*streamPath),
+			parser.WithRoot(*root),
+		},
 	}
 
-	log.Printf("generated csv file: %v\n", outfile.Name())
+	if *delimiter != "" {
+		comma, err := parser.ParseDelimiter(*delimiter)
+		if err != nil {
+			return nil, newUsageError("invalid -delimiter: %q", *delimiter)
+		}
+		a.opts = append(a.opts, parser.WithDelimiter(comma))
+	}
+
+	strategy, err := parser.ParseTruncateStrategy(*truncateStrategy)
+	if err != nil {
+		return nil, newUsageError("invalid -truncate-strategy: %s", *truncateStrategy)
+	}
+	a.opts = append(a.opts, parser.WithTruncateStrategy(strategy))
+
+	duplicates, err := parser.ParseDuplicateHeaderPolicy(*duplicateHeaders)
+	if err != nil {
+		return nil, newUsageError("invalid -duplicate-headers: %s", *duplicateHeaders)
+	}
+	a.opts = append(a.opts, parser.WithDuplicateHeaders(duplicates))
+
+	style, err := parser.ParseHeaderStyle(*headerStyle)
+	if err != nil {
+		return nil, newUsageError("invalid -header-style: %s", *headerStyle)
+	}
+	a.opts = append(a.opts, parser.WithHeaderStyle(style), parser.WithHeaderSeparator(*separator))
+
+	order, err := parser.ParseKeyOrder(*keyOrder)
+	if err != nil {
+		return nil, newUsageError("invalid -key-order: %s", *keyOrder)
+	}
+	a.opts = append(a.opts, parser.WithKeyOrder(order))
+
+	numFormat, err := parser.ParseNumberFormat(*numberFormat)
+	if err != nil {
+		return nil, newUsageError("invalid -number-format: %s", *numberFormat)
+	}
+	a.opts = append(a.opts, parser.WithNumberFormat(numFormat), parser.WithNumberPrecision(*numberPrecision))
+
+	mode, err := parser.ParseArrayMode(*arrayMode)
+	if err != nil {
+		return nil, newUsageError("invalid -arrays: %s", *arrayMode)
+	}
+	a.opts = append(a.opts, parser.WithArrayMode(mode), parser.WithMaxArrayElements(*maxArrayElements))
+
+	sibling, err := parser.ParseSiblingMode(*siblings)
+	if err != nil {
+		return nil, newUsageError("invalid -siblings: %s", *siblings)
+	}
+	zip, err := parser.ParseZipPolicy(*zipLengths)
+	if err != nil {
+		return nil, newUsageError("invalid -zip-lengths: %s", *zipLengths)
+	}
+	a.opts = append(a.opts, parser.WithSiblingMode(sibling), parser.WithZipPolicy(zip))
+
+	scalarMode, err := parser.ParseScalarArrayMode(*scalarArrays)
+	if err != nil {
+		return nil, newUsageError("invalid -scalar-arrays: %s", *scalarArrays)
+	}
+	a.opts = append(a.opts, parser.WithScalarArrayMode(scalarMode), parser.WithScalarArraySeparator(*scalarSeparator))
+
+	a.opts = append(a.opts, parser.WithMaxDepth(*maxDepth))
+
+	includes, err := parsePatterns("include", include)
+	if err != nil {
+		return nil, err
+	}
+	excludes, err := parsePatterns("exclude", exclude)
+	if err != nil {
+		return nil, err
+	}
+	a.opts = append(a.opts, parser.WithInclude(includes...), parser.WithExclude(excludes...))
+	a.opts = append(a.opts, parser.WithMaxRows(*maxRows), parser.WithMaxColumns(*maxColumns), parser.WithMaxCells(*maxCells))
+
+	empty, err := parser.ParseEmptyPolicy(*emptyPolicy)
+	if err != nil {
+		return nil, newUsageError("invalid -empty: %s", *emptyPolicy)
+	}
+	a.opts = append(a.opts, parser.WithEmptyPolicy(empty))
+
+	if *streamPath != "" && !*stream {
+		return nil, newUsageError("-stream-path requires -stream")
+	}
+
+	if *normalize && *stream {
+		return nil, newUsageError("-normalize can't be used with -stream")
+	}
+
+	if *mappingPath != "" {
+		if *normalize {
+			return nil, newUsageError("-mapping can't be used with -normalize")
+		}
+		mapping, err := parser.ReadMappingFile(*mappingPath)
+		if err != nil {
+			return nil, newUsageError("invalid -mapping: %v", oops.Cause(err))
+		}
+		a.opts = append(a.opts, parser.WithMapping(mapping))
+	}
+
+	if *root != "" && *stream {
+		return nil, newUsageError("-root can't be used with -stream, use -stream-path")
+	}
+
+	if err := parser.ValidateOptions(a.opts...); err != nil {
+		return nil, newUsageError("invalid flags: %v", oops.Cause(err))
+	}
+
+	// With no arguments, read from stdin if something is being piped to it.
+	a.infilePath = flags.Arg(0)
+	if flags.NArg() == 0 {
+		if !stdinIsPipe() {
+			return nil, newUsageError("please provide an input file")
+		}
+		a.infilePath = parser.StdioPath
+	}
+
+	// Write to stdout by default when reading from stdin, so the command can be
+	// used in the middle of a pipeline.
+	if flags.NArg() == 2 {
+		path := flags.Arg(1)
+		a.outfilePath = &path
+	} else if a.infilePath == parser.StdioPath && !a.normalize {
+		path := parser.StdioPath
+		a.outfilePath = &path
+	}
+
+	// Normalized tables are written to several files in a directory.
+	if a.normalize && a.outfilePath != nil && *a.outfilePath == parser.StdioPath {
+		return nil, newUsageError("-normalize needs an output directory, it can't write to stdout")
+	}
+
+	return a, nil
+}
+
+// stdinIsPipe returns true if stdin is connected to a pipe or a file rather than
+// a terminal.
+func stdinIsPipe() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice == 0
 }
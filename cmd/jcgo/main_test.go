@@ -2,7 +2,9 @@ package main
 
 import (
 	"encoding/csv"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/samsarahq/go/snapshotter"
@@ -15,6 +17,7 @@ func TestJCGOEndToEnd(t *testing.T) {
 
 	testcases := []struct {
 		description string
+		flags       []string
 		infilePath  string
 		outfilePath string
 	}{
@@ -23,26 +26,31 @@ func TestJCGOEndToEnd(t *testing.T) {
 			infilePath:  "testdata/json1.json",
 			outfilePath: "testdata/json1.output.csv",
 		},
+		{
+			description: "ndjson infilePath, valid outfilePath, expect success",
+			infilePath:  "testdata/json2.jsonl",
+			outfilePath: "testdata/json2.output.csv",
+		},
+		{
+			description: "ndjson input format flag, expect success",
+			flags:       []string{"-input-format", "ndjson"},
+			infilePath:  "testdata/json2.jsonl",
+			outfilePath: "testdata/json2.output.csv",
+		},
 	}
 
 	for _, testcase := range testcases {
 		t.Run(testcase.description, func(t *testing.T) {
-			// Save the existing command line args, and reset them at the end of
-			// this testcase.
-			oldArgs := os.Args
-			defer func() { os.Args = oldArgs }()
-
-			// Temporarily set command line args before running main func.
-			os.Args = []string{"dummy_process_name"}
+			args := append([]string{}, testcase.flags...)
 			if len(testcase.infilePath) > 0 {
-				os.Args = append(os.Args, testcase.infilePath)
+				args = append(args, testcase.infilePath)
 			}
 			if len(testcase.outfilePath) > 0 {
-				os.Args = append(os.Args, testcase.outfilePath)
+				args = append(args, testcase.outfilePath)
 			}
 
-			// Call the main function with the testcases's command line args.
-			main()
+			// Run with the testcases's command line args and expect success.
+			assert.Equal(t, 0, run(args, ioutil.Discard))
 
 			// Open the expected output file and expect no error.
 			file, err := os.Open(testcase.outfilePath)
@@ -58,3 +66,400 @@ func TestJCGOEndToEnd(t *testing.T) {
 		})
 	}
 }
+
+func TestJCGOStdio(t *testing.T) {
+	expected, err := ioutil.ReadFile("testdata/json1.output.csv")
+	assert.NoError(t, err)
+
+	testcases := []struct {
+		description string
+		args        []string
+	}{
+		{
+			description: "dash for input and output",
+			args:        []string{"-", "-"},
+		},
+		{
+			description: "dash for input, output defaults to stdout",
+			args:        []string{"-"},
+		},
+		{
+			description: "no arguments with piped stdin",
+			args:        nil,
+		},
+		{
+			description: "streaming dash for input and output",
+			args:        []string{"-stream", "-stream-path", "/data/organization/groups/0/dispatchRoute/auditLogs", "-", "-"},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			// Save the existing stdin and stdout, and reset them at the end of
+			// this testcase.
+			oldStdin, oldStdout := os.Stdin, os.Stdout
+			defer func() { os.Stdin, os.Stdout = oldStdin, oldStdout }()
+
+			// Use the input file as stdin, and a temporary file as stdout.
+			stdin, err := os.Open("testdata/json1.json")
+			assert.NoError(t, err)
+			defer stdin.Close()
+
+			stdout, err := ioutil.TempFile("", "jcgo_stdout")
+			assert.NoError(t, err)
+			defer os.Remove(stdout.Name())
+
+			os.Stdin, os.Stdout = stdin, stdout
+
+			// Run with the testcases's command line args and expect success.
+			assert.Equal(t, 0, run(testcase.args, ioutil.Discard))
+			stdout.Close()
+
+			actual, err := ioutil.ReadFile(stdout.Name())
+			assert.NoError(t, err)
+			assert.Equal(t, string(expected), string(actual))
+		})
+	}
+}
+
+func TestJCGOFlags(t *testing.T) {
+	testcases := []struct {
+		description string
+		flags       []string
+		outfileExt  string
+		expected    string
+	}{
+		{
+			description: "default flags",
+			expected:    "a_b,a_c,d\n1,,x\n2,z,y\n",
+		},
+		{
+			description: "no header truncation and custom separator",
+			flags:       []string{"-truncate=false", "-separator", "."},
+			expected:    "a.b,a.c,d\n1,,x\n2,z,y\n",
+		},
+		{
+			description: "escaped header style",
+			flags:       []string{"-truncate=false", "-header-style", "escaped", "-separator", "."},
+			expected:    "a.b,a.c,d\n1,,x\n2,z,y\n",
+		},
+		{
+			description: "pointer header style",
+			flags:       []string{"-truncate=false", "-header-style", "pointer"},
+			expected:    "/a/b,/a/c,/d\n1,,x\n2,z,y\n",
+		},
+		{
+			description: "null token",
+			flags:       []string{"-null", "NULL"},
+			expected:    "a_b,a_c,d\n1,NULL,x\n2,z,y\n",
+		},
+		{
+			description: "fixed number format",
+			flags:       []string{"-number-format", "fixed", "-number-precision", "2"},
+			expected:    "a_b,a_c,d\n1.00,,x\n2.00,z,y\n",
+		},
+		{
+			description: "max depth",
+			flags:       []string{"-max-depth", "1"},
+			expected:    "a,d\n\"{\"\"b\"\":1,\"\"c\"\":null}\",x\n\"{\"\"b\"\":2,\"\"c\"\":\"\"z\"\"}\",y\n",
+		},
+		{
+			description: "root",
+			flags:       []string{"-root", "$.a"},
+			expected:    "b,c\n1,\n2,z\n",
+		},
+		{
+			description: "include and exclude",
+			flags:       []string{"-include", "a_*", "-include", "d", "-exclude", "re:_c$"},
+			expected:    "a_b,d\n1,x\n2,y\n",
+		},
+		{
+			description: "mapping",
+			flags:       []string{"-mapping", "testdata/mapping.yaml"},
+			expected:    "letter,number,code\nx,1,none\ny,2,z\n",
+		},
+		{
+			description: "unique suffix truncation",
+			flags:       []string{"-truncate-strategy", "suffix"},
+			expected:    "b,c,d\n1,,x\n2,z,y\n",
+		},
+		{
+			description: "custom delimiter",
+			flags:       []string{"-delimiter", ";"},
+			expected:    "a_b;a_c;d\n1;;x\n2;z;y\n",
+		},
+		{
+			description: "tsv output format",
+			flags:       []string{"-output-format", "tsv"},
+			outfileExt:  ".tsv",
+			expected:    "a_b\ta_c\td\n1\t\tx\n2\tz\ty\n",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "jcgo")
+			assert.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			ext := testcase.outfileExt
+			if ext == "" {
+				ext = ".csv"
+			}
+			outfilePath := filepath.Join(dir, "output"+ext)
+
+			args := append(append([]string{}, testcase.flags...), "testdata/json3.jsonl", outfilePath)
+			assert.Equal(t, 0, run(args, ioutil.Discard))
+
+			actual, err := ioutil.ReadFile(outfilePath)
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expected, string(actual))
+		})
+	}
+}
+
+func TestJCGODuplicateHeaders(t *testing.T) {
+	testcases := []struct {
+		description string
+		flags       []string
+		expected    string
+		expectError bool
+	}{
+		{
+			description: "expect error by default",
+			expectError: true,
+		},
+		{
+			description: "suffix",
+			flags:       []string{"-duplicate-headers", "suffix"},
+			expected:    "a_b,a_b_2,c\n1,2,3\n",
+		},
+		{
+			description: "path",
+			flags:       []string{"-duplicate-headers", "path"},
+			expected:    "$.a.b,$.a_b,c\n1,2,3\n",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "jcgo")
+			assert.NoError(t, err)
+			defer os.RemoveAll(dir)
+
+			outfilePath := filepath.Join(dir, "output.csv")
+			args := append(append([]string{}, testcase.flags...), "testdata/duplicates.json", outfilePath)
+			if testcase.expectError {
+				assert.Equal(t, exitError, run(args, ioutil.Discard))
+				return
+			}
+			assert.Equal(t, 0, run(args, ioutil.Discard))
+
+			actual, err := ioutil.ReadFile(outfilePath)
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expected, string(actual))
+		})
+	}
+}
+
+func TestJCGONormalize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jcgo")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	outDir := filepath.Join(dir, "tables")
+	assert.Equal(t, 0, run([]string{"-normalize", "testdata/json1.json", outDir}, ioutil.Discard))
+
+	files, err := ioutil.ReadDir(outDir)
+	assert.NoError(t, err)
+
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	assert.Contains(t, names, "root.csv")
+	assert.Contains(t, names, "data_organization_groups_dispatchRoute_auditLogs_afterState.csv")
+
+	// Without -overwrite the existing directory isn't written to again.
+	assert.Equal(t, exitError, run([]string{"-normalize", "-overwrite=false", "testdata/json1.json", outDir}, ioutil.Discard))
+}
+
+func TestJCGOErrors(t *testing.T) {
+	testcases := []struct {
+		description string
+		args        []string
+		expected    int
+	}{
+		{
+			description: "help flag",
+			args:        []string{"-h"},
+			expected:    0,
+		},
+		{
+			description: "unknown flag",
+			args:        []string{"-nonexistent", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "too many arguments",
+			args:        []string{"testdata/json1.json", "one.csv", "two.csv"},
+			expected:    exitUsage,
+		},
+		{
+			description: "invalid input format",
+			args:        []string{"-input-format", "xml", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "invalid output format",
+			args:        []string{"-output-format", "xls", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "invalid delimiter",
+			args:        []string{"-delimiter", "ab", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "empty separator",
+			args:        []string{"-separator", "", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "invalid header style",
+			args:        []string{"-header-style", "json", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "backslash separator with escaped header style",
+			args:        []string{"-header-style", "escaped", "-separator", `\`, "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "invalid key order",
+			args:        []string{"-key-order", "reverse", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "invalid empty policy",
+			args:        []string{"-empty", "none", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "invalid number format",
+			args:        []string{"-number-format", "hex", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "number precision too high",
+			args:        []string{"-number-format", "fixed", "-number-precision", "1001", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "normalize with stream",
+			args:        []string{"-normalize", "-stream", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "normalize to stdout",
+			args:        []string{"-normalize", "testdata/json1.json", "-"},
+			expected:    exitUsage,
+		},
+		{
+			description: "invalid array mode",
+			args:        []string{"-arrays", "wide", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "negative max array elements",
+			args:        []string{"-arrays", "columns", "-max-array-elements", "-1", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "invalid sibling mode",
+			args:        []string{"-siblings", "cross", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "invalid zip policy",
+			args:        []string{"-siblings", "zip", "-zip-lengths", "longest", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "invalid scalar array mode",
+			args:        []string{"-scalar-arrays", "csv", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "negative max depth",
+			args:        []string{"-max-depth", "-2", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "negative max rows",
+			args:        []string{"-max-rows", "-1", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "too many rows",
+			args:        []string{"-max-rows", "1", "testdata/json1.json", "testdata/json1.limit.csv"},
+			expected:    exitError,
+		},
+		{
+			description: "invalid exclude pattern",
+			args:        []string{"-exclude", "re:(", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "missing mapping file",
+			args:        []string{"-mapping", "testdata/nonexistent.yaml", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "mapping with normalize",
+			args:        []string{"-mapping", "testdata/mapping.yaml", "-normalize", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "invalid duplicate header policy",
+			args:        []string{"-duplicate-headers", "rename", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "invalid truncate strategy",
+			args:        []string{"-truncate-strategy", "middle", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "invalid root",
+			args:        []string{"-root", "data.items", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "root with stream",
+			args:        []string{"-root", "/data", "-stream", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "stream path without stream",
+			args:        []string{"-stream-path", "/data", "testdata/json1.json"},
+			expected:    exitUsage,
+		},
+		{
+			description: "output file exists without overwrite",
+			args:        []string{"-overwrite=false", "testdata/json1.json", "testdata/json1.output.csv"},
+			expected:    exitError,
+		},
+		{
+			description: "nonexistent input file",
+			args:        []string{"testdata/nonexistent.json", "-"},
+			expected:    exitError,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			assert.Equal(t, testcase.expected, run(testcase.args, ioutil.Discard))
+		})
+	}
+}
@@ -7,4 +7,5 @@ require (
 	github.com/kylelemons/godebug v1.1.0 // indirect
 	github.com/samsarahq/go v0.0.0-20191220233105-8077c9fbaed5
 	github.com/stretchr/testify v1.6.1
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -1,12 +1,50 @@
 package object
 
 import (
-	"sort"
+	"strconv"
 
 	"github.com/samsarahq/go/oops"
 )
 
-// ArrayObj implements the Object interface for a JSON array.
+// ArrayMode describes how the elements of nested arrays are written.
+type ArrayMode int
+
+const (
+	// ArrayRows writes each element of an array in its own row(s), repeating
+	// the values around the array.
+	ArrayRows ArrayMode = iota
+	// ArrayColumns writes each element of an array in its own column(s), with
+	// the element's index as a key in the header, like `events_0_eventAt`. A
+	// value with arrays then takes a single row.
+	ArrayColumns
+)
+
+// String returns the name of the ArrayMode, as accepted by ParseArrayMode.
+func (m ArrayMode) String() string {
+	switch m {
+	case ArrayRows:
+		return "rows"
+	case ArrayColumns:
+		return "columns"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseArrayMode returns the ArrayMode with the given name, or an error if the
+// name doesn't match any ArrayMode.
+func ParseArrayMode(name string) (ArrayMode, error) {
+	for _, m := range []ArrayMode{ArrayRows, ArrayColumns} {
+		if m.String() == name {
+			return m, nil
+		}
+	}
+	return ArrayRows, oops.Errorf("unknown array mode: %s", name)
+}
+
+// ArrayObj implements the Object interface for a JSON array. The elements are
+// kept in the same order as the source array, so the rows parsed from them are
+// too.
 type ArrayObj struct {
 	*Prefix
 	Val []Object
@@ -14,43 +52,92 @@ type ArrayObj struct {
 
 // NewArrayObj returns a ArrayObj for the given input slice.
 func NewArrayObj(prefix string, input []interface{}) (*ArrayObj, error) {
+	return DefaultConfig().NewArrayObj(prefix, input)
+}
+
+// NewArrayObj returns a ArrayObj for the given input slice, built with the
+// Config's settings.
+func (c *Config) NewArrayObj(prefix string, input []interface{}) (*ArrayObj, error) {
+	return c.newArrayObj(prefix, input, c.rootScope())
+}
+
+// newArrayObj returns a ArrayObj for the given input slice, for an array in
+// the given scope. Its elements share the array's prefix, so they're in the
+// same scope.
+//
+// Elements left out by the Config's Include and Exclude patterns are dropped,
+// and the ArrayObj is nil if that leaves no elements.
+func (c *Config) newArrayObj(prefix string, input []interface{}, s scope) (*ArrayObj, error) {
 	var vals []Object
 
 	for _, v := range input {
-		obj, err := FromInterface(prefix, v)
+		obj, err := c.fromValue(prefix, v, s)
 		if err != nil {
 			return nil, oops.Wrapf(err, "unable to create ArrayObj")
 		}
+		if obj == nil {
+			continue
+		}
 
 		vals = append(vals, obj)
 	}
 
-	// Sort the Objects in the ArrayObj by their Prefix and return.
-	sort.Sort(ByPrefix(vals))
+	if len(vals) == 0 {
+		return nil, nil
+	}
+
 	return &ArrayObj{
 		NewPrefix(prefix),
 		vals,
 	}, nil
 }
 
-// Parse returns the 2d slice of strings for the given ArrayObj.
+// Parse returns the 2d slice of strings for the given ArrayObj, with a row for
+// each row parsed from its elements.
+//
+// Elements don't need to have the same keys. The header row is the union of
+// the headers of every element, in the order each header is first seen, and
+// each row is aligned to it with empty cells for the headers its element
+// doesn't have.
 func (o ArrayObj) Parse() ([][]string, error) {
-	var ret [][]string
+	parsed, err := o.parseElements()
+	if err != nil {
+		return nil, err
+	}
+	return MergeParsed(parsed...), nil
+}
+
+// parseElements returns the 2d slice of strings for each element of the
+// ArrayObj, in order.
+func (o ArrayObj) parseElements() ([][][]string, error) {
+	var parsed [][][]string
 
 	for _, item := range o.Val {
-		parsed, err := item.Parse()
+		p, err := item.Parse()
 		if err != nil {
 			return nil, oops.Wrapf(err, "unable to parse item: %+v", item)
 		}
+		parsed = append(parsed, p)
+	}
 
-		// If this is the first item we've parsed then set the first row in ret
-		// to the first row in the parsed result.
-		if ret == nil {
-			ret = append(ret, parsed[0])
-		}
+	return parsed, nil
+}
+
+// newIndexedMapObj returns a MapObj for the given input slice, with the index
+// of each element as its key, for the ArrayColumns mode. Only the first
+// MaxArrayElements elements are kept if the Config has a limit. The scope is
+// the array's scope.
+func (c *Config) newIndexedMapObj(prefix string, input []interface{}, s scope) (*MapObj, error) {
+	if c.MaxArrayElements > 0 && len(input) > c.MaxArrayElements {
+		input = input[:c.MaxArrayElements]
+	}
 
-		ret = append(ret, parsed[1:]...)
+	keys := make([]string, len(input))
+	vals := make(map[string]interface{}, len(input))
+	for i, v := range input {
+		keys[i] = strconv.Itoa(i)
+		vals[keys[i]] = v
 	}
 
-	return ret, nil
+	return c.newMapObj(prefix, keys, vals, s)
 }
@@ -0,0 +1,177 @@
+package object_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
+)
+
+func TestArrayObjRowOrder(t *testing.T) {
+	// Enough elements that an unstable sort would reorder them.
+	var events []interface{}
+	var expectedEvents [][]string
+	for i := 0; i < 100; i++ {
+		events = append(events, map[string]interface{}{"seq": float64(i)})
+		expectedEvents = append(expectedEvents, []string{strconv.Itoa(i)})
+	}
+
+	testcases := []struct {
+		description string
+		input       []interface{}
+		expected    [][]string
+	}{
+		{
+			description: "scalars",
+			input:       []interface{}{"c", "a", "b"},
+			expected:    [][]string{{""}, {"c"}, {"a"}, {"b"}},
+		},
+		{
+			description: "maps",
+			input: []interface{}{
+				map[string]interface{}{"name": "z"},
+				map[string]interface{}{"name": "a"},
+				map[string]interface{}{"name": "m"},
+			},
+			expected: [][]string{{"name"}, {"z"}, {"a"}, {"m"}},
+		},
+		{
+			description: "nested arrays",
+			input: []interface{}{
+				[]interface{}{"3", "1"},
+				[]interface{}{"2"},
+			},
+			expected: [][]string{{""}, {"3"}, {"1"}, {"2"}},
+		},
+		{
+			description: "maps with different keys",
+			input: []interface{}{
+				map[string]interface{}{"id": "1", "name": "a"},
+				map[string]interface{}{"id": "2", "email": "b@example.com"},
+				map[string]interface{}{"name": "c"},
+			},
+			expected: [][]string{
+				{"id", "name", "email"},
+				{"1", "a", ""},
+				{"2", "", "b@example.com"},
+				{"", "c", ""},
+			},
+		},
+		{
+			description: "maps with nested keys missing",
+			input: []interface{}{
+				map[string]interface{}{"a": map[string]interface{}{"b": "1"}},
+				map[string]interface{}{"a": map[string]interface{}{"c": "2"}, "d": "3"},
+			},
+			expected: [][]string{
+				{"a_b", "a_c", "d"},
+				{"1", "", ""},
+				{"", "2", "3"},
+			},
+		},
+		{
+			description: "many elements",
+			input:       events,
+			expected:    append([][]string{{"seq"}}, expectedEvents...),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			obj, err := oo.NewArrayObj("", testcase.input)
+			assert.NoError(t, err)
+
+			parsed, err := obj.Parse()
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expected, parsed)
+		})
+	}
+}
+
+func TestArrayColumns(t *testing.T) {
+	record := map[string]interface{}{
+		"id": "1",
+		"events": []interface{}{
+			map[string]interface{}{"eventAt": "10"},
+			map[string]interface{}{"eventAt": "20", "tags": []interface{}{"a", "b"}},
+		},
+	}
+
+	testcases := []struct {
+		description string
+		config      *oo.Config
+		input       interface{}
+		expected    [][]string
+	}{
+		{
+			description: "nested arrays become indexed columns",
+			config:      &oo.Config{Separator: "_", ArrayMode: oo.ArrayColumns},
+			input:       record,
+			expected: [][]string{
+				{"events_0_eventAt", "events_1_eventAt", "events_1_tags_0", "events_1_tags_1", "id"},
+				{"10", "20", "a", "b", "1"},
+			},
+		},
+		{
+			description: "root array elements stay rows",
+			config:      &oo.Config{Separator: "_", ArrayMode: oo.ArrayColumns},
+			input:       []interface{}{record, map[string]interface{}{"id": "2", "events": []interface{}{}}},
+			expected: [][]string{
+				{"events_0_eventAt", "events_1_eventAt", "events_1_tags_0", "events_1_tags_1", "id", "events"},
+				{"10", "20", "a", "b", "1", ""},
+				{"", "", "", "", "2", ""},
+			},
+		},
+		{
+			description: "arrays inside a root array",
+			config:      &oo.Config{Separator: "_", ArrayMode: oo.ArrayColumns},
+			input:       []interface{}{[]interface{}{"a", "b"}, []interface{}{"c"}},
+			expected:    [][]string{{"0", "1"}, {"a", "b"}, {"c", ""}},
+		},
+		{
+			description: "indices past the limit are left out",
+			config:      &oo.Config{Separator: "_", ArrayMode: oo.ArrayColumns, MaxArrayElements: 1},
+			input:       record,
+			expected:    [][]string{{"events_0_eventAt", "id"}, {"10", "1"}},
+		},
+		{
+			description: "indices in numeric order",
+			config:      &oo.Config{Separator: "_", ArrayMode: oo.ArrayColumns},
+			input:       map[string]interface{}{"a": []interface{}{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "10"}},
+			expected: [][]string{
+				{"a_0", "a_1", "a_2", "a_3", "a_4", "a_5", "a_6", "a_7", "a_8", "a_9", "a_10"},
+				{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "10"},
+			},
+		},
+		{
+			description: "pointer headers",
+			config:      &oo.Config{HeaderStyle: oo.HeaderPointer, ArrayMode: oo.ArrayColumns},
+			input:       map[string]interface{}{"a": []interface{}{"x"}},
+			expected:    [][]string{{"/a/0"}, {"x"}},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			obj, err := testcase.config.FromInterface("", testcase.input)
+			assert.NoError(t, err)
+
+			parsed, err := obj.Parse()
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expected, parsed)
+		})
+	}
+}
+
+func TestParseArrayMode(t *testing.T) {
+	for _, mode := range []oo.ArrayMode{oo.ArrayRows, oo.ArrayColumns} {
+		parsed, err := oo.ParseArrayMode(mode.String())
+		assert.NoError(t, err)
+		assert.Equal(t, mode, parsed)
+	}
+
+	_, err := oo.ParseArrayMode("wide")
+	assert.Error(t, err)
+}
@@ -0,0 +1,126 @@
+package object
+
+import (
+	"strings"
+
+	"github.com/samsarahq/go/oops"
+)
+
+// Config holds the settings used to build Objects from JSON values.
+//
+// The FromInterface, NewMapObj and NewArrayObj functions use the settings from
+// DefaultConfig, the methods of the same name on a Config use its settings.
+type Config struct {
+	// Separator is placed between the keys of nested values to build the
+	// column headers.
+	Separator string
+
+	// HeaderStyle sets how keys are joined and escaped to build the column
+	// headers.
+	HeaderStyle HeaderStyle
+
+	// KeyOrder sets the order of the columns built from the keys of a map.
+	KeyOrder KeyOrder
+
+	// EmptyPolicy sets how empty JSON arrays and maps are written.
+	EmptyPolicy EmptyPolicy
+
+	// ArrayMode sets how the elements of nested arrays are written.
+	ArrayMode ArrayMode
+
+	// SiblingMode sets how the rows of sibling values in a map are combined,
+	// and ZipPolicy how siblings of different lengths are zipped together with
+	// SiblingZip.
+	SiblingMode SiblingMode
+	ZipPolicy   ZipPolicy
+
+	// ScalarArrayMode sets how arrays whose elements are all scalar values are
+	// written. Arrays with any array or map elements follow the ArrayMode.
+	ScalarArrayMode ScalarArrayMode
+
+	// ScalarArraySeparator is placed between the elements of a scalar array
+	// joined into a single cell with ScalarArrayJoin.
+	ScalarArraySeparator string
+
+	// MaxArrayElements is the most elements of each array written in their own
+	// columns with ArrayColumns, later elements are left out. Zero means no
+	// limit.
+	MaxArrayElements int
+
+	// MaxDepth is the most keys in a column header. A map or array nested
+	// that deep is written as compact JSON in a single cell under its prefix,
+	// instead of being flattened further. Zero means no limit.
+	MaxDepth int
+
+	// MaxRows, MaxColumns and MaxCells are the most data rows, columns and
+	// cells a value can be parsed into, see CheckLimits. Zero means no limit.
+	MaxRows    int
+	MaxColumns int
+	MaxCells   int
+
+	// Include and Exclude select the values to write by their column headers.
+	// A value whose header matches an Exclude pattern is left out, along with
+	// everything nested in it. If there are any Include patterns, only values
+	// whose header, or the header of a value they're nested in, matches one
+	// are written.
+	Include []*PathPattern
+	Exclude []*PathPattern
+
+	// NullToken is the value used for JSON null values.
+	NullToken string
+
+	// NumberFormat sets how numbers are written.
+	NumberFormat NumberFormat
+
+	// NumberPrecision is the number of digits after the decimal point for the
+	// NumberFixed and NumberScientific formats, at most MaxFixedDigits. A
+	// negative precision uses the fewest digits needed to write the number
+	// exactly.
+	NumberPrecision int
+}
+
+// DefaultConfig returns a pointer to a Config with the default settings.
+func DefaultConfig() *Config {
+	return &Config{
+		Separator:   "_",
+		HeaderStyle: HeaderPlain,
+		KeyOrder:    KeyOrderAlphabetical,
+		EmptyPolicy: EmptyBlank,
+		NullToken:   "",
+
+		ArrayMode:            ArrayRows,
+		SiblingMode:          SiblingRepeat,
+		ZipPolicy:            ZipPad,
+		ScalarArrayMode:      ScalarArrayExpand,
+		ScalarArraySeparator: "|",
+
+		NumberFormat:    NumberOriginal,
+		NumberPrecision: -1,
+	}
+}
+
+// Validate returns an error if the Config's settings can't be used together.
+func (c *Config) Validate() error {
+	if c.MaxDepth < 0 {
+		return oops.Errorf("max depth can't be negative: %d", c.MaxDepth)
+	}
+	if c.MaxArrayElements < 0 {
+		return oops.Errorf("max array elements can't be negative: %d", c.MaxArrayElements)
+	}
+	if c.MaxRows < 0 || c.MaxColumns < 0 || c.MaxCells < 0 {
+		return oops.Errorf("output limits can't be negative: %d rows, %d columns, %d cells", c.MaxRows, c.MaxColumns, c.MaxCells)
+	}
+	if c.NumberPrecision > MaxFixedDigits {
+		return oops.Errorf("number precision can't be more than %d: %d", MaxFixedDigits, c.NumberPrecision)
+	}
+	if c.HeaderStyle == HeaderPointer {
+		return nil
+	}
+	if c.Separator == "" {
+		return oops.Errorf("header separator can't be empty")
+	}
+	if c.HeaderStyle == HeaderEscaped && strings.Contains(c.Separator, `\`) {
+		return oops.Errorf("header separator can't contain a backslash when keys are escaped: %s", c.Separator)
+	}
+	return nil
+}
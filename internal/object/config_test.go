@@ -0,0 +1,85 @@
+package object_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
+)
+
+func TestConfig(t *testing.T) {
+	input := map[string]interface{}{
+		"outer": map[string]interface{}{
+			"_inner": "val",
+			"empty":  nil,
+		},
+	}
+
+	testcases := []struct {
+		description string
+		config      *oo.Config
+		expected    [][]string
+	}{
+		{
+			description: "default config",
+			config:      oo.DefaultConfig(),
+			expected: [][]string{
+				{"outer__inner", "outer_empty"},
+				{"val", ""},
+			},
+		},
+		{
+			description: "custom separator",
+			config:      &oo.Config{Separator: "."},
+			expected: [][]string{
+				{"outer._inner", "outer.empty"},
+				{"val", ""},
+			},
+		},
+		{
+			description: "custom null token",
+			config:      &oo.Config{Separator: "_", NullToken: "NULL"},
+			expected: [][]string{
+				{"outer__inner", "outer_empty"},
+				{"val", "NULL"},
+			},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			obj, err := testcase.config.FromInterface("", input)
+			assert.NoError(t, err)
+
+			parsed, err := obj.Parse()
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expected, parsed)
+		})
+	}
+}
+
+func TestConfigNullToken(t *testing.T) {
+	input := []interface{}{
+		map[string]interface{}{"a": nil, "b": ""},
+		map[string]interface{}{"a": "", "b": nil},
+	}
+
+	for _, token := range []string{"", "NULL", `\N`, "<missing>"} {
+		t.Run(token, func(t *testing.T) {
+			cfg := oo.DefaultConfig()
+			cfg.NullToken = token
+
+			null, err := cfg.FromInterface("a", nil)
+			assert.NoError(t, err)
+			assert.Equal(t, oo.NewNullObj("a", token), null)
+
+			obj, err := cfg.FromInterface("", input)
+			assert.NoError(t, err)
+
+			parsed, err := obj.Parse()
+			assert.NoError(t, err)
+			assert.Equal(t, [][]string{{"a", "b"}, {token, ""}, {"", token}}, parsed)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package object
+
+import (
+	"github.com/samsarahq/go/oops"
+)
+
+// EmptyPolicy describes how empty JSON arrays and maps are written.
+type EmptyPolicy int
+
+const (
+	// EmptyBlank keeps the row of the parent value, with a blank cell for the
+	// empty value.
+	EmptyBlank EmptyPolicy = iota
+	// EmptyLiteral keeps the row of the parent value, with `[]` or `{}` in the
+	// cell for the empty value.
+	EmptyLiteral
+	// EmptyDrop drops the row of the parent value, and doesn't add a column
+	// for the empty value.
+	EmptyDrop
+)
+
+// String returns the name of the EmptyPolicy, as accepted by ParseEmptyPolicy.
+func (p EmptyPolicy) String() string {
+	switch p {
+	case EmptyBlank:
+		return "blank"
+	case EmptyLiteral:
+		return "literal"
+	case EmptyDrop:
+		return "drop"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseEmptyPolicy returns the EmptyPolicy with the given name, or an error if
+// the name doesn't match any EmptyPolicy.
+func ParseEmptyPolicy(name string) (EmptyPolicy, error) {
+	for _, p := range []EmptyPolicy{EmptyBlank, EmptyLiteral, EmptyDrop} {
+		if p.String() == name {
+			return p, nil
+		}
+	}
+	return EmptyBlank, oops.Errorf("unknown empty policy: %s", name)
+}
+
+// EmptyObj implements the Object interface for an empty JSON array or map. The
+// Val is the literal representation of the empty value, `[]` or `{}`.
+type EmptyObj struct {
+	*Prefix
+	Val    string
+	Policy EmptyPolicy
+}
+
+// NewEmptyObj returns an EmptyObj with the given literal value and policy.
+func NewEmptyObj(prefix string, literal string, policy EmptyPolicy) *EmptyObj {
+	return &EmptyObj{
+		NewPrefix(prefix),
+		literal,
+		policy,
+	}
+}
+
+// Parse returns the 2d slice of strings for the given EmptyObj, according to
+// its EmptyPolicy.
+//
+// An empty value without a prefix, like an empty map in an array at the root of
+// the document, has no key to use as a column header, so with the EmptyBlank
+// policy it doesn't get a column.
+func (o EmptyObj) Parse() ([][]string, error) {
+	switch o.Policy {
+	case EmptyLiteral:
+		return [][]string{{string(*o.Prefix)}, {o.Val}}, nil
+	case EmptyDrop:
+		return [][]string{{}}, nil
+	}
+
+	header := []string{string(*o.Prefix)}
+	if *o.Prefix == "" {
+		header = []string{}
+	}
+	return [][]string{header, make([]string, len(header))}, nil
+}
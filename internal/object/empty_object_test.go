@@ -0,0 +1,120 @@
+package object_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
+)
+
+func TestEmptyPolicy(t *testing.T) {
+	input := []interface{}{
+		map[string]interface{}{"id": "1", "tags": []interface{}{}},
+		map[string]interface{}{"id": "2", "meta": map[string]interface{}{"owner": map[string]interface{}{}}},
+		map[string]interface{}{"id": "3", "tags": []interface{}{"a", "b"}},
+		map[string]interface{}{},
+	}
+
+	testcases := []struct {
+		description string
+		policy      oo.EmptyPolicy
+		expected    [][]string
+	}{
+		{
+			description: "blank",
+			policy:      oo.EmptyBlank,
+			expected: [][]string{
+				{"id", "tags", "meta_owner"},
+				{"1", "", ""},
+				{"2", "", ""},
+				{"3", "a", ""},
+				{"3", "b", ""},
+				{"", "", ""},
+			},
+		},
+		{
+			description: "literal",
+			policy:      oo.EmptyLiteral,
+			expected: [][]string{
+				{"id", "tags", "meta_owner", ""},
+				{"1", "[]", "", ""},
+				{"2", "", "{}", ""},
+				{"3", "a", "", ""},
+				{"3", "b", "", ""},
+				{"", "", "", "{}"},
+			},
+		},
+		{
+			description: "drop",
+			policy:      oo.EmptyDrop,
+			expected: [][]string{
+				{"id", "tags"},
+				{"3", "a"},
+				{"3", "b"},
+			},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			cfg := oo.DefaultConfig()
+			cfg.EmptyPolicy = testcase.policy
+
+			obj, err := cfg.FromInterface("", input)
+			assert.NoError(t, err)
+
+			parsed, err := obj.Parse()
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expected, parsed)
+		})
+	}
+}
+
+func TestEmptyPolicyFirstKey(t *testing.T) {
+	// The empty value sorts before the other keys, so it's the first item of
+	// the map to be parsed.
+	input := map[string]interface{}{"a": []interface{}{}, "b": "1", "c": []interface{}{"2", "3"}}
+
+	testcases := []struct {
+		description string
+		policy      oo.EmptyPolicy
+		expected    [][]string
+	}{
+		{
+			description: "blank",
+			policy:      oo.EmptyBlank,
+			expected:    [][]string{{"a", "b", "c"}, {"", "1", "2"}, {"", "1", "3"}},
+		},
+		{
+			description: "drop",
+			policy:      oo.EmptyDrop,
+			expected:    [][]string{{"b", "c"}},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			cfg := oo.DefaultConfig()
+			cfg.EmptyPolicy = testcase.policy
+
+			obj, err := cfg.FromInterface("", input)
+			assert.NoError(t, err)
+
+			parsed, err := obj.Parse()
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expected, parsed)
+		})
+	}
+}
+
+func TestParseEmptyPolicy(t *testing.T) {
+	for _, policy := range []oo.EmptyPolicy{oo.EmptyBlank, oo.EmptyLiteral, oo.EmptyDrop} {
+		parsed, err := oo.ParseEmptyPolicy(policy.String())
+		assert.NoError(t, err)
+		assert.Equal(t, policy, parsed)
+	}
+
+	_, err := oo.ParseEmptyPolicy("none")
+	assert.Error(t, err)
+}
@@ -0,0 +1,38 @@
+package object
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// isContainer returns true if the given value is a JSON array or map.
+func isContainer(input interface{}) bool {
+	switch input.(type) {
+	case []interface{}, map[string]interface{}, *OrderedMap:
+		return true
+	}
+	return false
+}
+
+// encodeJSON returns the given value as compact JSON text. Unlike json.Marshal,
+// the characters `<`, `>` and `&` aren't escaped since the text is written to a
+// CSV cell rather than HTML.
+func encodeJSON(input interface{}) (string, error) {
+	b, err := marshalJSON(input)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// marshalJSON returns the given value as compact JSON, without escaping HTML
+// characters.
+func marshalJSON(input interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(input); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
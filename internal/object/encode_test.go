@@ -0,0 +1,92 @@
+package object_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
+)
+
+func TestMaxDepth(t *testing.T) {
+	ordered := oo.NewOrderedMap()
+	ordered.Set("z", json.Number("1.50"))
+	ordered.Set("a", "<b>")
+
+	input := map[string]interface{}{
+		"id": "1",
+		"a": map[string]interface{}{
+			"b": map[string]interface{}{"c": "deep", "d": []interface{}{"x", nil}},
+			"e": "shallow",
+		},
+		"o":    ordered,
+		"list": []interface{}{map[string]interface{}{"f": map[string]interface{}{"g": true}}},
+	}
+
+	testcases := []struct {
+		description string
+		config      *oo.Config
+		expected    [][]string
+	}{
+		{
+			description: "depth of one",
+			config:      &oo.Config{Separator: "_", MaxDepth: 1},
+			expected: [][]string{
+				{"a", "id", "list", "o"},
+				{`{"b":{"c":"deep","d":["x",null]},"e":"shallow"}`, "1", `[{"f":{"g":true}}]`, `{"z":1.50,"a":"<b>"}`},
+			},
+		},
+		{
+			description: "depth of two",
+			config:      &oo.Config{Separator: "_", MaxDepth: 2},
+			expected: [][]string{
+				{"a_b", "a_e", "id", "list_f", "o_a", "o_z"},
+				{`{"c":"deep","d":["x",null]}`, "shallow", "1", `{"g":true}`, "<b>", "1.50"},
+			},
+		},
+		{
+			description: "array indices count with array columns",
+			config:      &oo.Config{Separator: "_", MaxDepth: 2, ArrayMode: oo.ArrayColumns},
+			expected: [][]string{
+				{"a_b", "a_e", "id", "list_0", "o_a", "o_z"},
+				{`{"c":"deep","d":["x",null]}`, "shallow", "1", `{"f":{"g":true}}`, "<b>", "1.50"},
+			},
+		},
+		{
+			description: "no limit",
+			config:      &oo.Config{Separator: "_"},
+			expected: [][]string{
+				{"a_b_c", "a_b_d", "a_e", "id", "list_f_g", "o_a", "o_z"},
+				{"deep", "x", "shallow", "1", "true", "<b>", "1.50"},
+				{"deep", "", "shallow", "1", "true", "<b>", "1.50"},
+			},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			obj, err := testcase.config.FromInterface("", input)
+			assert.NoError(t, err)
+
+			parsed, err := obj.Parse()
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expected, parsed)
+		})
+	}
+}
+
+func TestMaxDepthRootArray(t *testing.T) {
+	cfg := &oo.Config{Separator: "_", MaxDepth: 1}
+	input := []interface{}{
+		map[string]interface{}{"a": map[string]interface{}{"b": "1"}},
+		map[string]interface{}{"a": []interface{}{"2"}},
+	}
+
+	obj, err := cfg.FromInterface("", input)
+	assert.NoError(t, err)
+
+	parsed, err := obj.Parse()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"a"}, {`{"b":"1"}`}, {`["2"]`}}, parsed)
+}
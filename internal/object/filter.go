@@ -0,0 +1,133 @@
+package object
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/samsarahq/go/oops"
+)
+
+// RegexpPatternPrefix marks a PathPattern as a regular expression rather than a
+// glob.
+const RegexpPatternPrefix = "re:"
+
+// PathPattern matches the column headers of the values to keep or leave out,
+// see the Include and Exclude settings of a Config.
+type PathPattern struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+// ParsePathPattern returns the PathPattern for the given pattern, or an error if
+// it's malformed.
+//
+// A pattern starting with RegexpPatternPrefix is a regular expression, which
+// matches if it matches any part of a header, like `re:^events_\d+_`. Any other
+// pattern is a glob that must match the whole header, where `*` matches any
+// run of characters, separators included, and `?` matches any one character.
+func ParsePathPattern(pattern string) (*PathPattern, error) {
+	expr := globRegexp(pattern)
+	if strings.HasPrefix(pattern, RegexpPatternPrefix) {
+		expr = strings.TrimPrefix(pattern, RegexpPatternPrefix)
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, oops.Wrapf(err, "invalid path pattern: %s", pattern)
+	}
+	return &PathPattern{pattern, re}, nil
+}
+
+// globRegexp returns the regular expression matching the same headers as the
+// given glob.
+func globRegexp(glob string) string {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return sb.String()
+}
+
+// String returns the pattern the PathPattern was parsed from.
+func (p *PathPattern) String() string {
+	return p.pattern
+}
+
+// Match returns true if the PathPattern matches the given column header.
+func (p *PathPattern) Match(header string) bool {
+	return p.re.MatchString(header)
+}
+
+// matchAny returns true if any of the given PathPatterns matches the given
+// column header.
+func matchAny(patterns []*PathPattern, header string) bool {
+	for _, p := range patterns {
+		if p.Match(header) {
+			return true
+		}
+	}
+	return false
+}
+
+// scope describes where a value sits in the input while Objects are built. The
+// depth is the number of keys in the value's prefix, and included is true if
+// the value is kept by the Config's Include patterns, because it or one of the
+// values it's nested in matches one.
+type scope struct {
+	depth    int
+	included bool
+}
+
+// rootScope returns the scope of the value at the root of the input.
+func (c *Config) rootScope() scope {
+	return scope{included: len(c.Include) == 0}
+}
+
+// child returns the scope of a value nested one key deeper than a value in the
+// given scope.
+func (s scope) child() scope {
+	return scope{s.depth + 1, s.included}
+}
+
+// filter returns false if the value with the given prefix is left out by the
+// Config's Exclude patterns, along with the given scope updated for the value
+// by the Config's Include patterns. The root of the input, without a prefix,
+// is never matched.
+func (c *Config) filter(prefix string, s scope) (bool, scope) {
+	if prefix == "" {
+		return true, s
+	}
+	if matchAny(c.Exclude, prefix) {
+		return false, s
+	}
+	if !s.included && matchAny(c.Include, prefix) {
+		s.included = true
+	}
+	return true, s
+}
+
+// isLeaf returns true if the given value in the given scope is written in a
+// single column, rather than flattened into the columns of its children.
+func (c *Config) isLeaf(input interface{}, s scope) bool {
+	if c.MaxDepth > 0 && s.depth >= c.MaxDepth {
+		return true
+	}
+	switch vv := input.(type) {
+	case map[string]interface{}:
+		return len(vv) == 0
+	case *OrderedMap:
+		return len(vv.Keys) == 0
+	case []interface{}:
+		return len(vv) == 0 || c.ScalarArrayMode != ScalarArrayExpand && isScalarArray(vv)
+	}
+	return true
+}
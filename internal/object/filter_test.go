@@ -0,0 +1,164 @@
+package object_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
+)
+
+// patterns returns the PathPatterns for the given patterns, which must be
+// valid.
+func patterns(t *testing.T, raw ...string) []*oo.PathPattern {
+	var ret []*oo.PathPattern
+	for _, r := range raw {
+		p, err := oo.ParsePathPattern(r)
+		assert.NoError(t, err)
+		ret = append(ret, p)
+	}
+	return ret
+}
+
+func TestPathPattern(t *testing.T) {
+	testcases := []struct {
+		pattern  string
+		header   string
+		expected bool
+	}{
+		{"id", "id", true},
+		{"id", "user_id", false},
+		{"*_id", "user_id", true},
+		{"*_id", "user_id_name", false},
+		{"events_*", "events_0_eventAt", true},
+		{"a?c", "abc", true},
+		{"a.c", "abc", false},
+		{"a[0]", "a[0]", true},
+		{"re:_id$", "user_id", true},
+		{"re:^events_\\d+_", "events_12_eventAt", true},
+		{"re:^events_\\d+_", "oldevents_1_eventAt", false},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.pattern+" "+testcase.header, func(t *testing.T) {
+			p, err := oo.ParsePathPattern(testcase.pattern)
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.pattern, p.String())
+			assert.Equal(t, testcase.expected, p.Match(testcase.header))
+		})
+	}
+
+	_, err := oo.ParsePathPattern("re:(")
+	assert.Error(t, err)
+}
+
+func TestIncludeExclude(t *testing.T) {
+	input := map[string]interface{}{
+		"id":   "1",
+		"meta": map[string]interface{}{"etag": "x", "debug": map[string]interface{}{"trace": "t"}},
+		"events": []interface{}{
+			map[string]interface{}{"at": "10", "type": "a", "raw": "r1"},
+			map[string]interface{}{"at": "20", "type": "b", "raw": "r2"},
+		},
+		"tags": []interface{}{"red", "blue"},
+	}
+
+	testcases := []struct {
+		description string
+		include     []string
+		exclude     []string
+		scalars     oo.ScalarArrayMode
+		expected    [][]string
+	}{
+		{
+			description: "exclude a subtree",
+			exclude:     []string{"events", "tags"},
+			expected: [][]string{
+				{"id", "meta_debug_trace", "meta_etag"},
+				{"1", "t", "x"},
+			},
+		},
+		{
+			description: "exclude leaves by glob",
+			exclude:     []string{"*_raw", "meta_*", "tags"},
+			expected: [][]string{
+				{"events_at", "events_type", "id"},
+				{"10", "a", "1"},
+				{"20", "b", "1"},
+			},
+		},
+		{
+			description: "include leaves by regexp",
+			include:     []string{"re:^(id|events_at)$"},
+			expected: [][]string{
+				{"events_at", "id"},
+				{"10", "1"},
+				{"20", "1"},
+			},
+		},
+		{
+			description: "include a subtree",
+			include:     []string{"meta"},
+			expected: [][]string{
+				{"meta_debug_trace", "meta_etag"},
+				{"t", "x"},
+			},
+		},
+		{
+			description: "exclude inside an included subtree",
+			include:     []string{"meta"},
+			exclude:     []string{"meta_debug"},
+			expected: [][]string{
+				{"meta_etag"},
+				{"x"},
+			},
+		},
+		{
+			description: "joined scalar array",
+			include:     []string{"tags", "id"},
+			scalars:     oo.ScalarArrayJoin,
+			expected: [][]string{
+				{"id", "tags"},
+				{"1", "red|blue"},
+			},
+		},
+		{
+			description: "nothing left",
+			include:     []string{"missing"},
+			expected:    [][]string{{}},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			config := oo.DefaultConfig()
+			config.Include = patterns(t, testcase.include...)
+			config.Exclude = patterns(t, testcase.exclude...)
+			config.ScalarArrayMode = testcase.scalars
+
+			obj, err := config.FromInterface("", input)
+			assert.NoError(t, err)
+
+			actual, err := obj.Parse()
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expected, actual)
+		})
+	}
+}
+
+func TestIncludeRootArray(t *testing.T) {
+	config := oo.DefaultConfig()
+	config.Include = patterns(t, "id")
+
+	input := []interface{}{
+		map[string]interface{}{"id": "1", "name": "a"},
+		map[string]interface{}{"name": "b"},
+		map[string]interface{}{"id": "3"},
+	}
+
+	obj, err := config.FromInterface("", input)
+	assert.NoError(t, err)
+	actual, err := obj.Parse()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{{"id"}, {"1"}, {"3"}}, actual)
+}
@@ -0,0 +1,36 @@
+package object
+
+import (
+	"github.com/samsarahq/go/oops"
+)
+
+// CheckLimits returns an error if parsing the given Object would give more rows,
+// columns or cells than the Config's limits allow. The shape of the output is
+// worked out without parsing the Object, so a value that would blow up is
+// caught before the rows are built.
+//
+// The limits apply to the rows built for every nested value as well, even if
+// some of them are dropped when combined with their siblings. The error names
+// the JSON Pointer of the deepest value that goes over a limit.
+func (c *Config) CheckLimits(obj Object) error {
+	if c.MaxRows == 0 && c.MaxColumns == 0 && c.MaxCells == 0 {
+		return nil
+	}
+	_, err := obj.shape(nil, c.checkShape)
+	return err
+}
+
+// checkShape returns an error if the given shape is over any of the Config's
+// limits.
+func (c *Config) checkShape(path []pathStep, s shape) error {
+	if c.MaxRows > 0 && s.rows > c.MaxRows {
+		return oops.Errorf("row limit of %d exceeded at %s", c.MaxRows, formatPointer(path))
+	}
+	if c.MaxColumns > 0 && len(s.headers) > c.MaxColumns {
+		return oops.Errorf("column limit of %d exceeded at %s", c.MaxColumns, formatPointer(path))
+	}
+	if c.MaxCells > 0 && s.rows*len(s.headers) > c.MaxCells {
+		return oops.Errorf("cell limit of %d exceeded at %s", c.MaxCells, formatPointer(path))
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package object_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
+)
+
+// sizedArray returns an array of n maps, each with the given key set to the
+// element's index.
+func sizedArray(n int, key string) []interface{} {
+	ret := make([]interface{}, n)
+	for i := range ret {
+		ret[i] = map[string]interface{}{key: float64(i)}
+	}
+	return ret
+}
+
+func TestCheckLimits(t *testing.T) {
+	nested := make([]interface{}, 10)
+	for i := range nested {
+		nested[i] = map[string]interface{}{"id": float64(i), "items": sizedArray(10, "v")}
+	}
+
+	testcases := []struct {
+		description string
+		config      *oo.Config
+		input       interface{}
+		expectedErr string
+	}{
+		{
+			description: "no limits",
+			config:      &oo.Config{Separator: "_"},
+			input:       map[string]interface{}{"a": nested},
+		},
+		{
+			description: "within limits",
+			config:      &oo.Config{Separator: "_", MaxRows: 100, MaxColumns: 3, MaxCells: 300},
+			input:       map[string]interface{}{"a": nested},
+		},
+		{
+			description: "too many rows from nested arrays",
+			config:      &oo.Config{Separator: "_", MaxRows: 99},
+			input:       map[string]interface{}{"a": nested},
+			expectedErr: "row limit of 99 exceeded at /a",
+		},
+		{
+			description: "too many rows in one nested array",
+			config:      &oo.Config{Separator: "_", MaxRows: 5},
+			input:       map[string]interface{}{"a": nested},
+			expectedErr: "row limit of 5 exceeded at /a/0/items",
+		},
+		{
+			description: "too many columns",
+			config:      &oo.Config{Separator: "_", MaxColumns: 2},
+			input:       map[string]interface{}{"a": "x", "b": map[string]interface{}{"c/d": "y", "e": "z"}},
+			expectedErr: "column limit of 2 exceeded at (root)",
+		},
+		{
+			description: "too many columns with array columns",
+			config:      &oo.Config{Separator: "_", MaxColumns: 5, ArrayMode: oo.ArrayColumns},
+			input:       map[string]interface{}{"a": map[string]interface{}{"b~c": sizedArray(10, "v")}},
+			expectedErr: "column limit of 5 exceeded at /a/b~0c",
+		},
+		{
+			description: "too many cells",
+			config:      &oo.Config{Separator: "_", MaxCells: 20},
+			input:       map[string]interface{}{"a": sizedArray(10, "v"), "b": "x", "c": "y"},
+			expectedErr: "cell limit of 20 exceeded at (root)",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			obj, err := testcase.config.FromInterface("", testcase.input)
+			assert.NoError(t, err)
+
+			err = testcase.config.CheckLimits(obj)
+			if testcase.expectedErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), testcase.expectedErr)
+			}
+		})
+	}
+}
+
+func TestCheckLimitsMatchesParse(t *testing.T) {
+	inputs := []interface{}{
+		"x",
+		[]interface{}{"a", "b", "c"},
+		map[string]interface{}{"a": sizedArray(3, "v"), "b": "x"},
+		map[string]interface{}{"a": sizedArray(3, "v"), "b": sizedArray(5, "w")},
+		map[string]interface{}{"a": []interface{}{}, "b": sizedArray(2, "w")},
+		[]interface{}{map[string]interface{}{"a": "x"}, map[string]interface{}{"b": "y"}, sizedArray(2, "a")},
+	}
+
+	configs := []*oo.Config{
+		{Separator: "_", EmptyPolicy: oo.EmptyBlank},
+		{Separator: "_", EmptyPolicy: oo.EmptyLiteral},
+		{Separator: "_", EmptyPolicy: oo.EmptyDrop},
+		{Separator: "_", SiblingMode: oo.SiblingZip, ZipPolicy: oo.ZipPad},
+	}
+
+	for _, c := range configs {
+		for _, input := range inputs {
+			config := *c
+			obj, err := config.FromInterface("", input)
+			assert.NoError(t, err)
+
+			parsed, err := obj.Parse()
+			assert.NoError(t, err)
+			rows, columns := len(parsed)-1, len(parsed[0])
+
+			// The Object fits exactly in limits of its own size, and goes over
+			// limits any smaller.
+			config.MaxRows, config.MaxColumns = rows, columns
+			assert.NoError(t, config.CheckLimits(obj), "input: %v", input)
+
+			if rows > 1 {
+				config.MaxRows = rows - 1
+				assert.Error(t, config.CheckLimits(obj), "input: %v", input)
+				config.MaxRows = rows
+			}
+			if columns > 1 {
+				config.MaxColumns = columns - 1
+				assert.Error(t, config.CheckLimits(obj), "input: %v", input)
+			}
+		}
+	}
+}
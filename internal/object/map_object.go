@@ -1,82 +1,169 @@
 package object
 
 import (
-	"fmt"
 	"sort"
 
 	"github.com/samsarahq/go/oops"
 )
 
-// MapObj implements the Object interface for a JSON map.
+// MapObj implements the Object interface for a JSON map. The SortedKeys are
+// in the order given by the KeyOrder of the Config the MapObj was built with.
+// The Siblings and ZipPolicy set how the rows of its values are combined.
 type MapObj struct {
 	*Prefix
 	SortedKeys []string
 	Val        map[string]Object
+	Siblings   SiblingMode
+	ZipPolicy  ZipPolicy
 }
 
 // NewMapObj returns a MapObj for the given input map.
 func NewMapObj(prefix string, input map[string]interface{}) (*MapObj, error) {
-	var keys []string
+	return DefaultConfig().NewMapObj(prefix, input)
+}
+
+// NewMapObj returns a MapObj for the given input map, built with the Config's
+// settings. A map[string]interface{} has no key order of its own, so its keys
+// are always sorted alphabetically.
+func (c *Config) NewMapObj(prefix string, input map[string]interface{}) (*MapObj, error) {
+	return c.newMapObj(prefix, sortedKeys(input), input, c.rootScope())
+}
+
+// NewOrderedMapObj returns a MapObj for the given input OrderedMap, built with
+// the Config's settings.
+func (c *Config) NewOrderedMapObj(prefix string, input *OrderedMap) (*MapObj, error) {
+	return c.newMapObj(prefix, c.orderedKeys(input), input.Values, c.rootScope())
+}
+
+// sortedKeys returns the keys of the given map sorted alphabetically.
+func sortedKeys(input map[string]interface{}) []string {
+	keys := make([]string, 0, len(input))
+	for k := range input {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// orderedKeys returns the keys of the given OrderedMap in the Config's KeyOrder.
+func (c *Config) orderedKeys(input *OrderedMap) []string {
+	keys := append([]string(nil), input.Keys...)
+	if c.KeyOrder == KeyOrderAlphabetical {
+		sort.Strings(keys)
+	}
+	return keys
+}
+
+// newMapObj returns a MapObj with the given keys, in the given order, and the
+// values for those keys in the input map, for a map in the given scope.
+//
+// Keys whose values are left out by the Config's Include and Exclude patterns
+// are dropped, and the MapObj is nil if that leaves no keys.
+func (c *Config) newMapObj(prefix string, keys []string, input map[string]interface{}, s scope) (*MapObj, error) {
 	vals := make(map[string]Object)
+	kept := make([]string, 0, len(keys))
 
-	for k, v := range input {
-		newPrefix := fmt.Sprintf("%s_%s", prefix, k)
-		obj, err := FromInterface(newPrefix, v)
+	for _, k := range keys {
+		newPrefix := c.joinPrefix(prefix, k)
+		obj, err := c.fromValue(newPrefix, input[k], s.child())
 		if err != nil {
 			return nil, oops.Wrapf(err, "unable to create MapObj")
 		}
+		if obj == nil {
+			continue
+		}
 		vals[k] = obj
-		keys = append(keys, k)
+		kept = append(kept, k)
+	}
+
+	if len(kept) == 0 {
+		return nil, nil
 	}
-	sort.Strings(keys)
 
 	return &MapObj{
 		NewPrefix(prefix),
-		keys,
+		kept,
 		vals,
+		c.SiblingMode,
+		c.ZipPolicy,
 	}, nil
 }
 
 // Parse returns the 2d slice of strings for the given MapObj.
+//
+// The rows parsed from each value are put side by side, so row i of the map
+// holds row i of each value. How values with fewer rows than the others fill
+// the remaining rows depends on the Siblings mode, see SiblingMode. When
+// zipping, a scalar value is repeated in every row, and an array has a row for
+// each element. A value with no rows, like an empty array with the EmptyDrop
+// policy, leaves the map with no rows.
+//
+// Every row is built in a new slice, so rows never share storage with each
+// other or with the rows of the values.
 func (o MapObj) Parse() ([][]string, error) {
-	var ret [][]string
+	var items [][][]string
+	var keys []string
+	var rows []int
+	var repeats []bool
+	header := []string{}
 
 	for _, key := range o.SortedKeys {
 		item := o.Val[key]
-		parsed, err := item.Parse()
+		parsed, err := o.parseValue(item)
 		if err != nil {
 			return nil, oops.Wrapf(err, "unable to parse item: %+v", item)
 		}
 
-		// If this is the first item we've parsed then we can initialize ret
-		// to that value and skip to parsing the next item.
-		if ret == nil {
-			ret = parsed
+		// An item without a header row has nothing to add.
+		if len(parsed) == 0 {
 			continue
 		}
+		items = append(items, parsed)
+		keys = append(keys, key)
+		rows = append(rows, len(parsed)-1)
+		repeats = append(repeats, o.repeats(item, len(parsed)-1))
+		header = append(header, parsed[0]...)
+	}
 
-		// Update the first row in ret with the new keys from the parsed value.
-		ret[0] = append(ret[0], parsed[0]...)
+	if items == nil {
+		return nil, nil
+	}
 
-		// If the parsed item is a simple scalar value, then set it for each
-		// existing row in ret and skip to parsing the next item.
-		if len(parsed) == 2 {
-			for i := 1; i < len(ret); i++ {
-				ret[i] = append(ret[i], parsed[1]...)
-			}
-			continue
-		}
+	n, err := o.rowCount(keys, rows, repeats)
+	if err != nil {
+		return nil, err
+	}
 
-		// If the parsed item is not a simple scalar then update each existing
-		// row, and add additional rows as needed.
-		lastRow := ret[len(ret)-1]
-		for i := 1; i < len(parsed); i++ {
-			if i == len(ret) {
-				ret = append(ret, lastRow)
+	ret := make([][]string, 1, n+1)
+	ret[0] = header
+	for i := 0; i < n; i++ {
+		row := make([]string, 0, len(header))
+		for j, item := range items {
+			switch {
+			case i < rows[j]:
+				row = append(row, item[i+1]...)
+			case repeats[j] || o.Siblings == SiblingRepeat:
+				row = append(row, item[rows[j]]...)
+			default:
+				row = append(row, make([]string, len(item[0]))...)
 			}
-			ret[i] = append(ret[i], parsed[i]...)
 		}
+		ret = append(ret, row)
 	}
 
 	return ret, nil
 }
+
+// parseValue returns the 2d slice of strings for the given value of the MapObj.
+// When zipping, an array is parsed so that each element fills a single row.
+func (o MapObj) parseValue(item Object) ([][]string, error) {
+	if o.Siblings == SiblingZip {
+		switch arr := item.(type) {
+		case ArrayObj:
+			return parseZipped(arr)
+		case *ArrayObj:
+			return parseZipped(*arr)
+		}
+	}
+	return item.Parse()
+}
@@ -1,6 +1,8 @@
 package object_test
 
 import (
+	"fmt"
+	"math/rand"
 	"testing"
 
 	"github.com/samsarahq/go/snapshotter"
@@ -49,3 +51,280 @@ func TestSimpleParseMapObject(t *testing.T) {
 		})
 	}
 }
+
+// source records where a generated scalar value came from: the column header
+// it belongs under, and the index of the element it's in for each array it's
+// inside, by array ID.
+type source struct {
+	header   string
+	elements map[int]int
+}
+
+// generator builds random nested JSON values, where every scalar value is a
+// unique token whose source is recorded. If singleRow is true, the elements of
+// arrays never hold arrays themselves, so each element parses into one row.
+type generator struct {
+	rand      *rand.Rand
+	sources   map[string]source
+	arrays    int
+	singleRow bool
+}
+
+// object returns a random map with the given prefix, inside the given array
+// elements.
+func (g *generator) object(prefix string, elements map[int]int, depth int) map[string]interface{} {
+	ret := make(map[string]interface{})
+	for _, key := range []string{"a", "b", "c", "d"}[:1+g.rand.Intn(4)] {
+		header := key
+		if prefix != "" {
+			header = prefix + "_" + key
+		}
+		ret[key] = g.value(header, elements, depth+1)
+	}
+	return ret
+}
+
+// value returns a random value with the given header, inside the given array
+// elements.
+func (g *generator) value(header string, elements map[int]int, depth int) interface{} {
+	kind := g.rand.Intn(4)
+	if depth >= 4 || (g.singleRow && len(elements) > 0 && kind >= 2) {
+		kind = 0
+	}
+
+	switch kind {
+	case 1:
+		return g.object(header, elements, depth)
+	case 2, 3:
+		id := g.arrays
+		g.arrays++
+
+		ret := make([]interface{}, g.rand.Intn(4))
+		for i := range ret {
+			inner := map[int]int{id: i}
+			for k, v := range elements {
+				inner[k] = v
+			}
+			ret[i] = g.value(header, inner, depth)
+		}
+		return ret
+	default:
+		token := fmt.Sprintf("v%d", len(g.sources))
+		g.sources[token] = source{header, elements}
+		return token
+	}
+}
+
+// expectedRows returns the number of rows the given value parses into.
+func expectedRows(input interface{}) int {
+	switch vv := input.(type) {
+	case map[string]interface{}:
+		n := 1
+		for _, v := range vv {
+			if r := expectedRows(v); r > n {
+				n = r
+			}
+		}
+		return n
+	case []interface{}:
+		if len(vv) == 0 {
+			return 1
+		}
+		n := 0
+		for _, v := range vv {
+			n += expectedRows(v)
+		}
+		return n
+	default:
+		return 1
+	}
+}
+
+// containsElements returns true if the array elements in a row include all of
+// the given array elements, both by array ID.
+func containsElements(row map[int]int, elements map[int]int) bool {
+	for id, idx := range elements {
+		if rowIdx, ok := row[id]; !ok || rowIdx != idx {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMapObjRowsProperty(t *testing.T) {
+	for seed := int64(0); seed < 200; seed++ {
+		g := &generator{rand: rand.New(rand.NewSource(seed)), sources: make(map[string]source)}
+		input := g.object("", map[int]int{}, 0)
+
+		obj, err := oo.NewMapObj("", input)
+		assert.NoError(t, err)
+		parsed, err := obj.Parse()
+		assert.NoError(t, err)
+
+		header := parsed[0]
+		assert.Equal(t, expectedRows(input), len(parsed)-1, "seed %d", seed)
+
+		columns := make(map[string]int)
+		for j, h := range header {
+			columns[h] = j
+		}
+
+		found := make(map[string]bool)
+		for i, row := range parsed[1:] {
+			assert.Equal(t, len(header), len(row), "seed %d row %d", seed, i)
+
+			// Every value is under its own header, and all the values
+			// in the row from the same array come from one element.
+			elements := make(map[int]int)
+			for j, cell := range row {
+				if cell == "" {
+					continue
+				}
+				src, ok := g.sources[cell]
+				assert.True(t, ok, "seed %d row %d: unknown value %q", seed, i, cell)
+				assert.Equal(t, src.header, header[j], "seed %d row %d", seed, i)
+				for id, idx := range src.elements {
+					if prev, ok := elements[id]; ok {
+						assert.Equal(t, prev, idx, "seed %d row %d: values from different elements of array %d", seed, i, id)
+					}
+					elements[id] = idx
+				}
+				found[cell] = true
+			}
+
+			// Every value is in each row holding the array elements
+			// it's inside, so no cell is dropped or left blank.
+			for token, src := range g.sources {
+				if !containsElements(elements, src.elements) {
+					continue
+				}
+				j, ok := columns[src.header]
+				if assert.True(t, ok, "seed %d: no column for %q", seed, src.header) {
+					assert.Equal(t, token, row[j], "seed %d row %d: missing value under %q", seed, i, src.header)
+				}
+			}
+		}
+
+		// Every value is in the output.
+		for token := range g.sources {
+			assert.True(t, found[token], "seed %d: value %q isn't in any row", seed, token)
+		}
+
+		// Changing a row doesn't change any other row.
+		for i := 1; i < len(parsed); i++ {
+			before := make([][]string, len(parsed))
+			for j, row := range parsed {
+				before[j] = append([]string(nil), row...)
+			}
+			for j := range parsed[i] {
+				parsed[i][j] = "changed"
+			}
+			for j := range parsed {
+				if j != i {
+					assert.Equal(t, before[j], parsed[j], "seed %d: changing row %d changed row %d", seed, i, j)
+				}
+			}
+			parsed[i] = before[i]
+		}
+	}
+}
+
+// zipRows returns the values in each row the given value parses into with
+// SiblingZip, built straight from the value. The rows of sibling values are
+// lined up by index, and a value with a single row that isn't an array repeats
+// it next to every row of its siblings. Returns true if the value is an array.
+func zipRows(input interface{}, shortest bool) ([]map[string]bool, bool) {
+	switch vv := input.(type) {
+	case map[string]interface{}:
+		var children [][]map[string]bool
+		var repeats []bool
+		n := -1
+		for _, v := range vv {
+			rows, isArray := zipRows(v, shortest)
+			repeat := len(rows) == 1 && !isArray
+			children = append(children, rows)
+			repeats = append(repeats, repeat)
+			if !repeat && (n == -1 || (shortest && len(rows) < n) || (!shortest && len(rows) > n)) {
+				n = len(rows)
+			}
+		}
+		if n == -1 {
+			n = 1
+		}
+
+		ret := make([]map[string]bool, n)
+		for r := range ret {
+			ret[r] = make(map[string]bool)
+			for i, rows := range children {
+				var cells map[string]bool
+				switch {
+				case repeats[i]:
+					cells = rows[0]
+				case r < len(rows):
+					cells = rows[r]
+				}
+				for token := range cells {
+					ret[r][token] = true
+				}
+			}
+		}
+		return ret, false
+	case []interface{}:
+		// An empty array is a single blank cell.
+		if len(vv) == 0 {
+			return []map[string]bool{{}}, false
+		}
+		var ret []map[string]bool
+		for _, v := range vv {
+			rows, _ := zipRows(v, shortest)
+			ret = append(ret, rows...)
+		}
+		return ret, true
+	default:
+		return []map[string]bool{{vv.(string): true}}, false
+	}
+}
+
+func TestMapObjZipProperty(t *testing.T) {
+	for _, policy := range []oo.ZipPolicy{oo.ZipPad, oo.ZipTruncate} {
+		t.Run(policy.String(), func(t *testing.T) {
+			config := oo.DefaultConfig()
+			config.SiblingMode = oo.SiblingZip
+			config.ZipPolicy = policy
+
+			for seed := int64(0); seed < 200; seed++ {
+				g := &generator{rand: rand.New(rand.NewSource(seed)), sources: make(map[string]source), singleRow: true}
+				input := g.object("", map[int]int{}, 0)
+
+				obj, err := config.NewMapObj("", input)
+				assert.NoError(t, err)
+				parsed, err := obj.Parse()
+				if !assert.NoError(t, err, "seed %d", seed) {
+					continue
+				}
+
+				expected, _ := zipRows(input, policy == oo.ZipTruncate)
+				if !assert.Equal(t, len(expected), len(parsed)-1, "seed %d", seed) {
+					continue
+				}
+
+				// Every row holds exactly the values zipped into it, each under
+				// its own header.
+				header := parsed[0]
+				for i, row := range parsed[1:] {
+					actual := make(map[string]bool)
+					for j, cell := range row {
+						if cell == "" {
+							continue
+						}
+						actual[cell] = true
+						if src, ok := g.sources[cell]; assert.True(t, ok, "seed %d row %d: unknown value %q", seed, i, cell) {
+							assert.Equal(t, src.header, header[j], "seed %d row %d", seed, i)
+						}
+					}
+					assert.Equal(t, expected[i], actual, "seed %d row %d", seed, i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package object
+
+// MergeParsed combines several parsed 2d slices of strings into one with a
+// single shared header row.
+//
+// The merged header is the union of the headers of all the inputs, in the
+// order each header is first seen. Every data row is aligned to the merged
+// header, and cells for headers that a row's input didn't have are left empty.
+//
+// A header that appears more than once in an input keeps a column for each
+// time it appears, the nth one is aligned with the nth column of that name.
+func MergeParsed(parsed ...[][]string) [][]string {
+	var header []string
+	index := make(map[string][]int)
+
+	// Build the merged header row, and the column each input header maps to.
+	columns := make([][]int, len(parsed))
+	for i, p := range parsed {
+		if len(p) == 0 {
+			continue
+		}
+		seen := make(map[string]int)
+		for _, h := range p[0] {
+			n := seen[h]
+			seen[h]++
+			if n == len(index[h]) {
+				index[h] = append(index[h], len(header))
+				header = append(header, h)
+			}
+			columns[i] = append(columns[i], index[h][n])
+		}
+	}
+
+	if header == nil {
+		return nil
+	}
+	ret := [][]string{header}
+
+	// Copy each data row into a new row of the merged width.
+	for i, p := range parsed {
+		if len(p) == 0 {
+			continue
+		}
+		for _, row := range p[1:] {
+			merged := make([]string, len(header))
+			for j, cell := range row {
+				merged[columns[i][j]] = cell
+			}
+			ret = append(ret, merged)
+		}
+	}
+
+	return ret
+}
@@ -0,0 +1,79 @@
+package object_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
+)
+
+func TestMergeParsed(t *testing.T) {
+	testcases := []struct {
+		description string
+		input       [][][]string
+		expected    [][]string
+	}{
+		{
+			description: "no input",
+			input:       nil,
+			expected:    nil,
+		},
+		{
+			description: "single input is unchanged",
+			input: [][][]string{
+				{{"a", "b"}, {"1", "2"}},
+			},
+			expected: [][]string{
+				{"a", "b"},
+				{"1", "2"},
+			},
+		},
+		{
+			description: "same headers",
+			input: [][][]string{
+				{{"a", "b"}, {"1", "2"}},
+				{{"a", "b"}, {"3", "4"}, {"5", "6"}},
+			},
+			expected: [][]string{
+				{"a", "b"},
+				{"1", "2"},
+				{"3", "4"},
+				{"5", "6"},
+			},
+		},
+		{
+			description: "different headers",
+			input: [][][]string{
+				{{"a", "b"}, {"1", "2"}},
+				{{"c", "a"}, {"3", "4"}},
+			},
+			expected: [][]string{
+				{"a", "b", "c"},
+				{"1", "2", ""},
+				{"4", "", "3"},
+			},
+		},
+		{
+			description: "repeated headers",
+			input: [][][]string{
+				{{"a", "a"}, {"1", "2"}},
+				{{"b", "a"}, {"3", "4"}},
+				{{"a", "a", "a"}, {"5", "6", "7"}},
+			},
+			expected: [][]string{
+				{"a", "a", "b", "a"},
+				{"1", "2", "", ""},
+				{"4", "", "3", ""},
+				{"5", "6", "", "7"},
+			},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			actual := oo.MergeParsed(testcase.input...)
+			assert.Equal(t, testcase.expected, actual)
+		})
+	}
+}
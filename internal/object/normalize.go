@@ -0,0 +1,282 @@
+package object
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/samsarahq/go/oops"
+)
+
+// The generated columns that link normalized tables together. Every table has
+// a RowIDColumn numbering its rows from 1. The rows of a child table also have
+// a ParentRowIDColumn with the RowIDColumn of the row holding their array in
+// the parent table, and an OrdinalColumn with their index in that array.
+const (
+	RowIDColumn       = "_row_id"
+	ParentRowIDColumn = "_parent_row_id"
+	OrdinalColumn     = "_ordinal"
+)
+
+// NestedArraySuffix is added to the name of a table to name the table of the
+// arrays nested directly inside its rows' arrays, which have no key of their
+// own.
+const NestedArraySuffix = "[]"
+
+// Table is a named 2d slice of strings, the first row of the Data holds the
+// column headers.
+type Table struct {
+	// Name is the header of the array the rows of the Table come from. The
+	// root table has an empty Name.
+	Name string
+	Data [][]string
+	// Sources holds the JSONPath of the values in each column, in the same
+	// order as the headers, see Sources. The generated columns have an empty
+	// source.
+	Sources []string
+}
+
+// Normalize splits the given Object into linked tables instead of parsing it
+// into a single one. There's a root table with a row for the Object, or a row
+// for each element if it's an ArrayObj, and a child table for each array path
+// with a row for each element of those arrays. The headers of a child table
+// are relative to its array.
+//
+// The tables are returned in the order their arrays are first seen, starting
+// with the root table. Returns an error if a header collides with one of the
+// generated columns, or if two different array paths build the same table
+// name, like a key `a_b` and a key `b` inside the elements of `a`.
+//
+// Different paths can also build the same header within a table, and those
+// columns are kept apart, with their Sources telling them apart.
+//
+// An empty array has no rows in its table. Empty maps are written as cells
+// according to the Config's EmptyPolicy, but never drop a row.
+func (c *Config) Normalize(obj Object) ([]Table, error) {
+	n := &normalizer{
+		cfg:      c,
+		bySource: make(map[string]*tableBuilder),
+		sources:  make(map[string]string),
+	}
+
+	root, err := n.table("", "", formatSource(nil), RowIDColumn)
+	if err != nil {
+		return nil, err
+	}
+	if arr, ok := obj.(*ArrayObj); ok {
+		for i, item := range arr.Val {
+			path := []pathStep{{key: strconv.Itoa(i), index: true}}
+			if err := n.addRow(root, item, path, nil); err != nil {
+				return nil, oops.Wrapf(err, "unable to normalize root array element")
+			}
+		}
+	} else if err := n.addRow(root, obj, nil, nil); err != nil {
+		return nil, oops.Wrapf(err, "unable to normalize root object")
+	}
+
+	var ret []Table
+	for _, t := range n.tables {
+		ret = append(ret, t.build())
+	}
+	return ret, nil
+}
+
+// normalizer holds the tables built while normalizing an Object.
+//
+// The child tables are found by the source of their array, so arrays at
+// different paths never share a table, and the sources map holds the source
+// each table name is used for.
+type normalizer struct {
+	cfg      *Config
+	tables   []*tableBuilder
+	bySource map[string]*tableBuilder
+	sources  map[string]string
+}
+
+// table creates a table with the given name, base prefix and generated columns
+// for the rows from the given source. Returns an error if the name is already
+// used by the table of another source.
+func (n *normalizer) table(name, base, source string, generated ...string) (*tableBuilder, error) {
+	if other, ok := n.sources[name]; ok {
+		return nil, oops.Errorf("table %q is built from arrays at both %s and %s", name, other, source)
+	}
+
+	t := &tableBuilder{
+		name:  name,
+		base:  base,
+		index: make(map[string]int),
+	}
+	for _, col := range generated {
+		t.addColumn(col, "")
+	}
+	t.generated = len(generated)
+
+	n.tables = append(n.tables, t)
+	n.sources[name] = source
+	return t, nil
+}
+
+// childTable returns the table for the array with the given prefix at the
+// given path inside the rows of the given table.
+func (n *normalizer) childTable(parent *tableBuilder, prefix string, path []pathStep) (*tableBuilder, error) {
+	source := formatSource(path)
+	if t, ok := n.bySource[source]; ok {
+		return t, nil
+	}
+
+	// An array directly inside an array has the same prefix as the table of
+	// the outer array.
+	name := prefix
+	if name == parent.base {
+		name = parent.name + NestedArraySuffix
+	}
+	t, err := n.table(name, prefix, source, RowIDColumn, ParentRowIDColumn, OrdinalColumn)
+	if err != nil {
+		return nil, err
+	}
+	n.bySource[source] = t
+	return t, nil
+}
+
+// addRow adds a row for the given Object at the given path to the given table.
+// The keys are the values of the generated columns after the RowIDColumn.
+func (n *normalizer) addRow(t *tableBuilder, obj Object, path []pathStep, keys []string) error {
+	row := t.newRow(keys)
+	return n.fill(t, row, obj, path)
+}
+
+// fill sets the cells of the given row of the given table from the given
+// Object at the given path. Each ArrayObj adds rows to a child table instead.
+func (n *normalizer) fill(t *tableBuilder, row int, obj Object, path []pathStep) error {
+	switch o := obj.(type) {
+	case *MapObj:
+		for _, key := range o.SortedKeys {
+			if err := n.fill(t, row, o.Val[key], childPath(path, pathStep{key: key})); err != nil {
+				return err
+			}
+		}
+		return nil
+	case *EmptyObj:
+		if o.Val == "[]" {
+			_, err := n.childTable(t, o.getPrefix(), path)
+			return err
+		}
+	case *ArrayObj:
+		child, err := n.childTable(t, o.getPrefix(), path)
+		if err != nil {
+			return err
+		}
+
+		parentID := t.rowID(row)
+		for i, item := range o.Val {
+			itemPath := childPath(path, pathStep{key: strconv.Itoa(i), index: true})
+			if err := n.addRow(child, item, itemPath, []string{parentID, strconv.Itoa(i)}); err != nil {
+				return oops.Wrapf(err, "unable to normalize element %d of array: %s", i, child.name)
+			}
+		}
+		return nil
+	}
+
+	parsed, err := obj.Parse()
+	if err != nil {
+		return oops.Wrapf(err, "unable to parse item: %+v", obj)
+	}
+	if len(parsed) < 2 {
+		return nil
+	}
+	for i, header := range parsed[0] {
+		if err := t.set(row, n.relativeHeader(t.base, header), formatSource(path), parsed[1][i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// relativeHeader returns the given header without the given base prefix of its
+// table. A header that is nothing but the base prefix, i.e. for an array of
+// scalar values, becomes "value".
+func (n *normalizer) relativeHeader(base, header string) string {
+	if header == base {
+		return "value"
+	}
+	if base == "" {
+		return header
+	}
+
+	sep := n.cfg.Separator
+	if n.cfg.HeaderStyle == HeaderPointer {
+		sep = "/"
+	}
+	return strings.TrimPrefix(header, base+sep)
+}
+
+// tableBuilder collects the columns and rows of a Table. The columns are found
+// by their source, so values from different paths never share a column, even
+// if their headers are the same.
+type tableBuilder struct {
+	name      string
+	base      string
+	columns   []string
+	sources   []string
+	index     map[string]int
+	generated int
+	rows      [][]string
+}
+
+// addColumn adds a column with the given header and source, and returns its
+// index.
+func (t *tableBuilder) addColumn(header, source string) int {
+	t.index[source] = len(t.columns)
+	t.columns = append(t.columns, header)
+	t.sources = append(t.sources, source)
+	return t.index[source]
+}
+
+// newRow adds a row with the next row ID and the given values for the other
+// generated columns, and returns its index.
+func (t *tableBuilder) newRow(keys []string) int {
+	row := append([]string{strconv.Itoa(len(t.rows) + 1)}, keys...)
+	t.rows = append(t.rows, row)
+	return len(t.rows) - 1
+}
+
+// rowID returns the value of the RowIDColumn of the given row.
+func (t *tableBuilder) rowID(row int) string {
+	return t.rows[row][0]
+}
+
+// set sets the cell of the given row in the column for the given source,
+// adding a column with the given header if it's the first time the source is
+// seen. Returns an error if the header is one of the generated columns.
+func (t *tableBuilder) set(row int, header, source, value string) error {
+	col, ok := t.index[source]
+	if !ok {
+		for _, generated := range t.columns[:t.generated] {
+			if header == generated {
+				return oops.Errorf("column %q in table %q collides with a generated column", header, t.name)
+			}
+		}
+		col = t.addColumn(header, source)
+	}
+
+	for len(t.rows[row]) <= col {
+		t.rows[row] = append(t.rows[row], "")
+	}
+	t.rows[row][col] = value
+	return nil
+}
+
+// build returns the Table, with every row padded to the full width.
+func (t *tableBuilder) build() Table {
+	data := [][]string{t.columns}
+	for _, row := range t.rows {
+		padded := make([]string, len(t.columns))
+		copy(padded, row)
+		data = append(data, padded)
+	}
+
+	return Table{
+		Name:    t.name,
+		Data:    data,
+		Sources: t.sources,
+	}
+}
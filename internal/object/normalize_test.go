@@ -0,0 +1,199 @@
+package object_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
+)
+
+func TestNormalize(t *testing.T) {
+	record := func(id float64, events []interface{}, states []interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"id":         id,
+			"owner":      map[string]interface{}{"name": "a"},
+			"events":     events,
+			"afterState": states,
+		}
+	}
+
+	testcases := []struct {
+		description string
+		config      *oo.Config
+		input       interface{}
+		expected    []oo.Table
+		expectError bool
+	}{
+		{
+			description: "arrays become child tables",
+			config:      oo.DefaultConfig(),
+			input: []interface{}{
+				record(1,
+					[]interface{}{
+						map[string]interface{}{"type": "start", "tags": []interface{}{"x", "y"}},
+						map[string]interface{}{"type": "stop"},
+					},
+					[]interface{}{"on"},
+				),
+				record(2, []interface{}{map[string]interface{}{"type": "start"}}, []interface{}{}),
+			},
+			expected: []oo.Table{
+				{
+					Name: "",
+					Data: [][]string{
+						{"_row_id", "id", "owner_name"},
+						{"1", "1", "a"},
+						{"2", "2", "a"},
+					},
+					Sources: []string{"", "$[*].id", "$[*].owner.name"},
+				},
+				{
+					Name: "afterState",
+					Data: [][]string{
+						{"_row_id", "_parent_row_id", "_ordinal", "value"},
+						{"1", "1", "0", "on"},
+					},
+					Sources: []string{"", "", "", "$[*].afterState[*]"},
+				},
+				{
+					Name: "events",
+					Data: [][]string{
+						{"_row_id", "_parent_row_id", "_ordinal", "type"},
+						{"1", "1", "0", "start"},
+						{"2", "1", "1", "stop"},
+						{"3", "2", "0", "start"},
+					},
+					Sources: []string{"", "", "", "$[*].events[*].type"},
+				},
+				{
+					Name: "events_tags",
+					Data: [][]string{
+						{"_row_id", "_parent_row_id", "_ordinal", "value"},
+						{"1", "1", "0", "x"},
+						{"2", "1", "1", "y"},
+					},
+					Sources: []string{"", "", "", "$[*].events[*].tags[*]"},
+				},
+			},
+		},
+		{
+			description: "nested arrays",
+			config:      oo.DefaultConfig(),
+			input: map[string]interface{}{
+				"grid": []interface{}{
+					[]interface{}{"a", "b"},
+					[]interface{}{"c"},
+				},
+			},
+			expected: []oo.Table{
+				{
+					Name:    "",
+					Data:    [][]string{{"_row_id"}, {"1"}},
+					Sources: []string{""},
+				},
+				{
+					Name:    "grid",
+					Data:    [][]string{{"_row_id", "_parent_row_id", "_ordinal"}, {"1", "1", "0"}, {"2", "1", "1"}},
+					Sources: []string{"", "", ""},
+				},
+				{
+					Name: "grid[]",
+					Data: [][]string{
+						{"_row_id", "_parent_row_id", "_ordinal", "value"},
+						{"1", "1", "0", "a"},
+						{"2", "1", "1", "b"},
+						{"3", "2", "0", "c"},
+					},
+					Sources: []string{"", "", "", "$.grid[*][*]"},
+				},
+			},
+		},
+		{
+			description: "empty arrays have no rows",
+			config:      oo.DefaultConfig(),
+			input:       map[string]interface{}{"id": "1", "tags": []interface{}{}, "meta": map[string]interface{}{}},
+			expected: []oo.Table{
+				{
+					Name:    "",
+					Data:    [][]string{{"_row_id", "id", "meta"}, {"1", "1", ""}},
+					Sources: []string{"", "$.id", "$.meta"},
+				},
+				{
+					Name:    "tags",
+					Data:    [][]string{{"_row_id", "_parent_row_id", "_ordinal"}},
+					Sources: []string{"", "", ""},
+				},
+			},
+		},
+		{
+			description: "scalar root",
+			config:      oo.DefaultConfig(),
+			input:       "one",
+			expected: []oo.Table{
+				{
+					Name:    "",
+					Data:    [][]string{{"_row_id", "value"}, {"1", "one"}},
+					Sources: []string{"", "$"},
+				},
+			},
+		},
+		{
+			description: "pointer headers",
+			config:      &oo.Config{HeaderStyle: oo.HeaderPointer},
+			input: map[string]interface{}{
+				"a": map[string]interface{}{"b": []interface{}{map[string]interface{}{"c": "1"}}},
+			},
+			expected: []oo.Table{
+				{
+					Name:    "",
+					Data:    [][]string{{"_row_id"}, {"1"}},
+					Sources: []string{""},
+				},
+				{
+					Name:    "/a/b",
+					Data:    [][]string{{"_row_id", "_parent_row_id", "_ordinal", "c"}, {"1", "1", "0", "1"}},
+					Sources: []string{"", "", "", "$.a.b[*].c"},
+				},
+			},
+		},
+		{
+			description: "values from different paths with the same header keep their own columns",
+			config:      oo.DefaultConfig(),
+			input:       map[string]interface{}{"a_b": "1", "a": map[string]interface{}{"b": "2"}},
+			expected: []oo.Table{
+				{
+					Name:    "",
+					Data:    [][]string{{"_row_id", "a_b", "a_b"}, {"1", "2", "1"}},
+					Sources: []string{"", "$.a.b", "$.a_b"},
+				},
+			},
+		},
+		{
+			description: "expect error for arrays at different paths with the same table name",
+			config:      oo.DefaultConfig(),
+			input: map[string]interface{}{
+				"a":   []interface{}{map[string]interface{}{"b": []interface{}{"1"}}},
+				"a_b": []interface{}{"2"},
+			},
+			expectError: true,
+		},
+		{
+			description: "expect error for a key that collides with a generated column",
+			config:      oo.DefaultConfig(),
+			input:       map[string]interface{}{"_row_id": "1"},
+			expectError: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			obj, err := testcase.config.FromInterface("", testcase.input)
+			assert.NoError(t, err)
+
+			tables, err := testcase.config.Normalize(obj)
+			assert.Equal(t, testcase.expectError, err != nil)
+			assert.Equal(t, testcase.expected, tables)
+		})
+	}
+}
@@ -0,0 +1,294 @@
+package object
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/samsarahq/go/oops"
+)
+
+// NumberFormat describes how numbers are written.
+type NumberFormat int
+
+const (
+	// NumberOriginal writes numbers exactly as they appear in the document.
+	NumberOriginal NumberFormat = iota
+	// NumberFixed writes numbers in fixed point notation, like `1234.5`.
+	NumberFixed
+	// NumberScientific writes numbers in scientific notation, like
+	// `1.2345e+03`.
+	NumberScientific
+)
+
+// String returns the name of the NumberFormat, as accepted by
+// ParseNumberFormat.
+func (f NumberFormat) String() string {
+	switch f {
+	case NumberOriginal:
+		return "original"
+	case NumberFixed:
+		return "fixed"
+	case NumberScientific:
+		return "scientific"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseNumberFormat returns the NumberFormat with the given name, or an error
+// if the name doesn't match any NumberFormat.
+func ParseNumberFormat(name string) (NumberFormat, error) {
+	for _, f := range []NumberFormat{NumberOriginal, NumberFixed, NumberScientific} {
+		if f.String() == name {
+			return f, nil
+		}
+	}
+	return NumberOriginal, oops.Errorf("unknown number format: %s", name)
+}
+
+// MaxFixedDigits is the most digits a number is written with in the
+// NumberFixed format, and the highest NumberPrecision. A number that needs more
+// digits, like `1e1000`, is written in the NumberScientific format instead.
+// It's enough to write every float64 in full.
+const MaxFixedDigits = 1000
+
+// NumberObj implements the Object interface for a numeric value. The Val holds
+// the text of the number as it appears in the document, so no precision is
+// lost for numbers that don't fit in a float64 or an int64.
+type NumberObj struct {
+	*Prefix
+	Val json.Number
+
+	format    NumberFormat
+	precision int
+}
+
+// NewNumberObj returns a NumberObj for the given input float.
+func NewNumberObj(prefix string, input float64) *NumberObj {
+	return NewJSONNumberObj(prefix, numberFromFloat(input))
+}
+
+// NewJSONNumberObj returns a NumberObj for the given input json.Number.
+func NewJSONNumberObj(prefix string, input json.Number) *NumberObj {
+	return DefaultConfig().NewJSONNumberObj(prefix, input)
+}
+
+// NewJSONNumberObj returns a NumberObj for the given input json.Number, which
+// is written with the Config's NumberFormat and NumberPrecision.
+func (c *Config) NewJSONNumberObj(prefix string, input json.Number) *NumberObj {
+	return &NumberObj{
+		Prefix:    NewPrefix(prefix),
+		Val:       input,
+		format:    c.NumberFormat,
+		precision: c.NumberPrecision,
+	}
+}
+
+// numberFromFloat returns the json.Number for the given float. An integer
+// value is written without a fractional part, i.e. "345" not "345.0".
+func numberFromFloat(f float64) json.Number {
+	return json.Number(strconv.FormatFloat(f, 'f', -1, 64))
+}
+
+// Parse returns the 2d slice of strings for the given NumberObj, with the
+// number written in the NumberObj's format. Returns an error if the NumberObj's
+// value isn't a valid number.
+//
+// Numbers are rounded as decimals, with halves rounded away from zero, so
+// `2.675` with two digits is `2.68`. A number that needs more than
+// MaxFixedDigits digits in the NumberFixed format is written in the
+// NumberScientific format instead.
+func (o NumberObj) Parse() ([][]string, error) {
+	stringVal := o.Val.String()
+	if o.format == NumberFixed || o.format == NumberScientific {
+		d, err := parseDecimal(stringVal)
+		if err != nil {
+			return nil, oops.Wrapf(err, "invalid number: %s", stringVal)
+		}
+
+		if o.format == NumberFixed && d.fixedDigits(o.precision) <= MaxFixedDigits {
+			stringVal = d.formatFixed(o.precision)
+		} else {
+			stringVal = d.formatScientific(o.precision)
+		}
+	}
+
+	return [][]string{
+		{string(*o.Prefix)},
+		{stringVal},
+	}, nil
+}
+
+// numberRegexp matches the text of a JSON number, capturing the sign, the
+// integer and fractional digits, and the exponent.
+var numberRegexp = regexp.MustCompile(`^(-)?([0-9]+)(?:\.([0-9]+))?(?:[eE]([+-]?[0-9]+))?$`)
+
+// decimal is a number read from its decimal text, with the value
+// `digits * 10^exp`. The digits have no leading or trailing zeros, and are
+// empty for zero.
+//
+// The number is formatted by working on its digits as text, so the time taken
+// only depends on the length of the text and the output, however big or small
+// the exponent is. The exponent must fit in 32 bits, so working it out for the
+// output can't overflow.
+type decimal struct {
+	neg    bool
+	digits string
+	exp    int
+}
+
+// parseDecimal returns the decimal for the given JSON number text, or an error
+// if the text isn't a valid number.
+func parseDecimal(text string) (decimal, error) {
+	m := numberRegexp.FindStringSubmatch(text)
+	if m == nil {
+		return decimal{}, oops.Errorf("malformed number")
+	}
+
+	var exp int
+	if m[4] != "" {
+		var err error
+		parsed, err := strconv.ParseInt(m[4], 10, 32)
+		if err != nil {
+			return decimal{}, oops.Errorf("exponent out of range: %s", m[4])
+		}
+		exp = int(parsed)
+	}
+
+	// Move the decimal point to the end of the fractional digits, then drop
+	// the zeros that don't change the value.
+	digits := m[2] + m[3]
+	exp -= len(m[3])
+	digits = strings.TrimLeft(digits, "0")
+	trimmed := strings.TrimRight(digits, "0")
+	exp += len(digits) - len(trimmed)
+
+	if trimmed == "" {
+		return decimal{}, nil
+	}
+	return decimal{neg: m[1] == "-", digits: trimmed, exp: exp}, nil
+}
+
+// sign returns the sign to write in front of the decimal.
+func (d decimal) sign() string {
+	if d.neg {
+		return "-"
+	}
+	return ""
+}
+
+// fixedPrecision returns the number of digits after the decimal point to write
+// the decimal with in fixed point notation for the given precision. A negative
+// precision uses the fewest digits needed to write the number exactly.
+func (d decimal) fixedPrecision(precision int) int {
+	if precision >= 0 {
+		return precision
+	}
+	if d.exp < 0 {
+		return -d.exp
+	}
+	return 0
+}
+
+// fixedDigits returns the number of digits in the decimal written in fixed
+// point notation with the given precision, ignoring any carry from rounding.
+func (d decimal) fixedDigits(precision int) int {
+	whole := len(d.digits) + d.exp
+	if whole < 1 {
+		whole = 1
+	}
+	return whole + d.fixedPrecision(precision)
+}
+
+// formatFixed returns the decimal in fixed point notation with the given number
+// of digits after the decimal point. A negative precision uses the fewest digits
+// needed to write the number exactly.
+func (d decimal) formatFixed(precision int) string {
+	precision = d.fixedPrecision(precision)
+
+	// Find the digits of the number times 10^precision, rounded to an integer.
+	var scaled string
+	if shift := d.exp + precision; shift >= 0 {
+		scaled = d.digits + strings.Repeat("0", shift)
+	} else {
+		scaled, _ = roundDigits(d.digits, len(d.digits)+shift)
+	}
+	if scaled == "" {
+		scaled = "0"
+	}
+
+	if precision == 0 {
+		return d.sign() + scaled
+	}
+	if len(scaled) <= precision {
+		scaled = strings.Repeat("0", precision-len(scaled)+1) + scaled
+	}
+	point := len(scaled) - precision
+	return d.sign() + scaled[:point] + "." + scaled[point:]
+}
+
+// formatScientific returns the decimal in scientific notation, like
+// `1.2345e+03`, with the given number of digits after the decimal point. A
+// negative precision uses the fewest digits needed to write the number exactly.
+func (d decimal) formatScientific(precision int) string {
+	if precision < 0 {
+		precision = len(d.digits) - 1
+		if precision < 0 {
+			precision = 0
+		}
+	}
+
+	exp := 0
+	if d.digits != "" {
+		exp = len(d.digits) - 1 + d.exp
+	}
+
+	// Rounding can carry the mantissa up to 10, i.e. 9.99 with one digit.
+	mantissa, carried := roundDigits(d.digits, precision+1)
+	if carried {
+		exp++
+		mantissa = mantissa[:precision+1]
+	}
+
+	text := mantissa[:1]
+	if precision > 0 {
+		text += "." + mantissa[1:]
+	}
+
+	sign := '+'
+	if exp < 0 {
+		sign = '-'
+		exp = -exp
+	}
+	return fmt.Sprintf("%s%se%c%02d", d.sign(), text, sign, exp)
+}
+
+// roundDigits returns the first n of the given digits, rounded with halves away
+// from zero, or padded with zeros if there are fewer than n digits. Returns
+// true if rounding carried into a new leading digit, so the result has n+1
+// digits, like `999` rounded to `1000`.
+func roundDigits(digits string, n int) (string, bool) {
+	if n < 0 {
+		return "", false
+	}
+	if n >= len(digits) {
+		return digits + strings.Repeat("0", n-len(digits)), false
+	}
+
+	kept := []byte(digits[:n])
+	if digits[n] < '5' {
+		return string(kept), false
+	}
+
+	for i := len(kept) - 1; i >= 0; i-- {
+		if kept[i] != '9' {
+			kept[i]++
+			return string(kept), false
+		}
+		kept[i] = '0'
+	}
+	return "1" + string(kept), true
+}
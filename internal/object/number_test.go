@@ -0,0 +1,233 @@
+package object_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
+)
+
+func TestNumberFormat(t *testing.T) {
+	testcases := []struct {
+		description string
+		input       json.Number
+		format      oo.NumberFormat
+		precision   int
+		expected    string
+	}{
+		{
+			description: "original large integer",
+			input:       "4337769816123456789",
+			format:      oo.NumberOriginal,
+			expected:    "4337769816123456789",
+		},
+		{
+			description: "original integer beyond int64",
+			input:       "123456789012345678901234567890",
+			format:      oo.NumberOriginal,
+			expected:    "123456789012345678901234567890",
+		},
+		{
+			description: "original keeps the source text",
+			input:       "1.50e3",
+			format:      oo.NumberOriginal,
+			expected:    "1.50e3",
+		},
+		{
+			description: "fixed large integer",
+			input:       "4337769816123456789",
+			format:      oo.NumberFixed,
+			precision:   -1,
+			expected:    "4337769816123456789",
+		},
+		{
+			description: "fixed exponent",
+			input:       "1.5e3",
+			format:      oo.NumberFixed,
+			precision:   -1,
+			expected:    "1500",
+		},
+		{
+			description: "fixed decimals",
+			input:       "2.675",
+			format:      oo.NumberFixed,
+			precision:   2,
+			expected:    "2.68",
+		},
+		{
+			description: "fixed decimals padded",
+			input:       "-7",
+			format:      oo.NumberFixed,
+			precision:   3,
+			expected:    "-7.000",
+		},
+		{
+			description: "scientific",
+			input:       "1234.5",
+			format:      oo.NumberScientific,
+			precision:   -1,
+			expected:    "1.2345e+03",
+		},
+		{
+			description: "scientific with precision",
+			input:       "0.000123456",
+			format:      oo.NumberScientific,
+			precision:   2,
+			expected:    "1.23e-04",
+		},
+		{
+			description: "scientific rounding carries into the exponent",
+			input:       "9.96",
+			format:      oo.NumberScientific,
+			precision:   1,
+			expected:    "1.0e+01",
+		},
+		{
+			description: "scientific negative",
+			input:       "-0.5",
+			format:      oo.NumberScientific,
+			precision:   -1,
+			expected:    "-5e-01",
+		},
+		{
+			description: "scientific zero",
+			input:       "0",
+			format:      oo.NumberScientific,
+			precision:   2,
+			expected:    "0.00e+00",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			cfg := oo.DefaultConfig()
+			cfg.NumberFormat = testcase.format
+			cfg.NumberPrecision = testcase.precision
+
+			parsed, err := cfg.NewJSONNumberObj("n", testcase.input).Parse()
+			assert.NoError(t, err)
+			assert.Equal(t, [][]string{{"n"}, {testcase.expected}}, parsed)
+		})
+	}
+}
+
+func TestNumberFormatExtremeExponents(t *testing.T) {
+	testcases := []struct {
+		description string
+		input       json.Number
+		format      oo.NumberFormat
+		precision   int
+		expected    string
+	}{
+		{
+			description: "scientific very small",
+			input:       "1e-300000",
+			format:      oo.NumberScientific,
+			precision:   -1,
+			expected:    "1e-300000",
+		},
+		{
+			description: "scientific very large",
+			input:       "-12.5e300000",
+			format:      oo.NumberScientific,
+			precision:   2,
+			expected:    "-1.25e+300001",
+		},
+		{
+			description: "fixed very small falls back to scientific",
+			input:       "1e-100000",
+			format:      oo.NumberFixed,
+			precision:   -1,
+			expected:    "1e-100000",
+		},
+		{
+			description: "fixed very small rounded",
+			input:       "1e-100000",
+			format:      oo.NumberFixed,
+			precision:   2,
+			expected:    "0.00",
+		},
+		{
+			description: "fixed very large falls back to scientific",
+			input:       "1.5e1000000000",
+			format:      oo.NumberFixed,
+			precision:   1,
+			expected:    "1.5e+1000000000",
+		},
+		{
+			description: "fixed smallest float64",
+			input:       "4.9406564584124654e-324",
+			format:      oo.NumberFixed,
+			precision:   -1,
+			expected:    "0." + strings.Repeat("0", 323) + "49406564584124654",
+		},
+		{
+			description: "fixed at the digit limit",
+			input:       "1e999",
+			format:      oo.NumberFixed,
+			precision:   -1,
+			expected:    "1" + strings.Repeat("0", 999),
+		},
+		{
+			description: "fixed past the digit limit",
+			input:       "1e999",
+			format:      oo.NumberFixed,
+			precision:   1,
+			expected:    "1.0e+999",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			cfg := oo.DefaultConfig()
+			cfg.NumberFormat = testcase.format
+			cfg.NumberPrecision = testcase.precision
+
+			parsed, err := cfg.NewJSONNumberObj("n", testcase.input).Parse()
+			assert.NoError(t, err)
+			assert.Equal(t, [][]string{{"n"}, {testcase.expected}}, parsed)
+		})
+	}
+}
+
+func TestNumberFromFloat(t *testing.T) {
+	testcases := []struct {
+		input    float64
+		expected string
+	}{
+		{input: 5, expected: "5"},
+		{input: 5.5, expected: "5.5"},
+		{input: -0.25, expected: "-0.25"},
+		{input: 1e21, expected: "1000000000000000000000"},
+	}
+
+	for _, testcase := range testcases {
+		parsed, err := oo.NewNumberObj("n", testcase.input).Parse()
+		assert.NoError(t, err)
+		assert.Equal(t, testcase.expected, parsed[1][0])
+	}
+}
+
+func TestInvalidNumber(t *testing.T) {
+	cfg := oo.DefaultConfig()
+	cfg.NumberFormat = oo.NumberFixed
+
+	for _, input := range []json.Number{"not a number", "1e9999999999"} {
+		_, err := cfg.NewJSONNumberObj("n", input).Parse()
+		assert.Error(t, err)
+	}
+}
+
+func TestParseNumberFormat(t *testing.T) {
+	for _, format := range []oo.NumberFormat{oo.NumberOriginal, oo.NumberFixed, oo.NumberScientific} {
+		parsed, err := oo.ParseNumberFormat(format.String())
+		assert.NoError(t, err)
+		assert.Equal(t, format, parsed)
+	}
+
+	_, err := oo.ParseNumberFormat("hex")
+	assert.Error(t, err)
+}
@@ -3,6 +3,7 @@
 package object
 
 import (
+	"encoding/json"
 	"strconv"
 
 	"github.com/samsarahq/go/oops"
@@ -18,9 +19,6 @@ func (p Prefix) getPrefix() string {
 
 // NewPrefix returns a pointer to a Prefix for the given string.
 func NewPrefix(p string) *Prefix {
-	if len(p) > 0 && p[0] == '_' {
-		p = p[1:]
-	}
 	pp := Prefix(p)
 	return &pp
 }
@@ -28,39 +26,132 @@ func NewPrefix(p string) *Prefix {
 // Object is representation of a JSON object.
 type Object interface {
 	getPrefix() string
+	shape(path []pathStep, check checkFunc) (shape, error)
 	Parse() ([][]string, error)
 }
 
-// ByPrefix implements the sort.Interface on the Prefix field.
-type ByPrefix []Object
-
-func (objs ByPrefix) Len() int           { return len(objs) }
-func (objs ByPrefix) Less(i, j int) bool { return objs[i].getPrefix() < objs[j].getPrefix() }
-func (objs ByPrefix) Swap(i, j int)      { objs[i], objs[j] = objs[j], objs[i] }
-
 // FromInterface returns the Object for the given input interface and returns an
 // error if the interface is of an invalid type.
 func FromInterface(prefix string, input interface{}) (Object, error) {
+	return DefaultConfig().FromInterface(prefix, input)
+}
+
+// FromInterface returns the Object for the given input interface, built with
+// the Config's settings, and returns an error if the interface is of an
+// invalid type.
+//
+// The input is the root of the values to convert, so an input array is always
+// an ArrayObj with a row for each of its elements. Arrays nested inside the
+// input follow the Config's ArrayMode. If the Config's Include and Exclude
+// patterns leave out every value, the result is an EmptyObj without any
+// columns.
+func (c *Config) FromInterface(prefix string, input interface{}) (Object, error) {
+	if vv, ok := input.([]interface{}); ok && len(vv) > 0 {
+		obj, err := c.NewArrayObj(prefix, vv)
+		if err != nil {
+			return nil, oops.Wrapf(err, "unable to create ArrayObj for interface: %+v", vv)
+		}
+		if obj == nil {
+			return NewEmptyObj(prefix, "[]", EmptyDrop), nil
+		}
+		return obj, nil
+	}
+
+	obj, err := c.fromValue(prefix, input, c.rootScope())
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return NewEmptyObj(prefix, "{}", EmptyDrop), nil
+	}
+	return obj, nil
+}
+
+// fromValue returns the Object for the given value nested inside the input of
+// FromInterface, in the given scope, and returns an error if the value is of an
+// invalid type. A map or array at the Config's MaxDepth is written as a JSON
+// string instead of being flattened.
+//
+// The Object is nil if the value is left out by the Config's Include and
+// Exclude patterns. Values are left out before their Objects are built, so an
+// excluded map or array costs nothing.
+func (c *Config) fromValue(prefix string, input interface{}, s scope) (Object, error) {
+	keep, s := c.filter(prefix, s)
+	if !keep || !s.included && c.isLeaf(input, s) {
+		return nil, nil
+	}
+
+	if c.MaxDepth > 0 && s.depth >= c.MaxDepth && isContainer(input) {
+		encoded, err := encodeJSON(input)
+		if err != nil {
+			return nil, oops.Wrapf(err, "unable to encode value below max depth: %+v", input)
+		}
+		return NewStringObj(prefix, encoded), nil
+	}
+
 	switch vv := input.(type) {
 	case nil:
-		return NewStringObj(prefix, ""), nil
+		return NewNullObj(prefix, c.NullToken), nil
 	case string:
 		return NewStringObj(prefix, vv), nil
 	case bool:
 		return NewBoolObj(prefix, vv), nil
 	case float64:
-		return NewNumberObj(prefix, vv), nil
+		return c.NewJSONNumberObj(prefix, numberFromFloat(vv)), nil
+	case json.Number:
+		return c.NewJSONNumberObj(prefix, vv), nil
 	case map[string]interface{}:
-		obj, err := NewMapObj(prefix, vv)
+		if len(vv) == 0 {
+			return NewEmptyObj(prefix, "{}", c.EmptyPolicy), nil
+		}
+		obj, err := c.newMapObj(prefix, sortedKeys(vv), vv, s)
+		if err != nil {
+			return nil, oops.Wrapf(err, "unable to create MapObj for interface: %+v", vv)
+		}
+		if obj == nil {
+			return nil, nil
+		}
+		return obj, nil
+	case *OrderedMap:
+		if len(vv.Keys) == 0 {
+			return NewEmptyObj(prefix, "{}", c.EmptyPolicy), nil
+		}
+		obj, err := c.newMapObj(prefix, c.orderedKeys(vv), vv.Values, s)
 		if err != nil {
 			return nil, oops.Wrapf(err, "unable to create MapObj for interface: %+v", vv)
 		}
+		if obj == nil {
+			return nil, nil
+		}
 		return obj, nil
 	case []interface{}:
-		obj, err := NewArrayObj(prefix, vv)
+		if len(vv) == 0 {
+			return NewEmptyObj(prefix, "[]", c.EmptyPolicy), nil
+		}
+		if c.ScalarArrayMode != ScalarArrayExpand && isScalarArray(vv) {
+			obj, err := c.newScalarArrayObj(prefix, vv, s)
+			if err != nil {
+				return nil, oops.Wrapf(err, "unable to create StringObj for interface: %+v", vv)
+			}
+			return obj, nil
+		}
+		if c.ArrayMode == ArrayColumns {
+			obj, err := c.newIndexedMapObj(prefix, vv, s)
+			if err != nil {
+				return nil, oops.Wrapf(err, "unable to create MapObj for interface: %+v", vv)
+			}
+			if obj == nil {
+				return nil, nil
+			}
+			return obj, nil
+		}
+		obj, err := c.newArrayObj(prefix, vv, s)
 		if err != nil {
 			return nil, oops.Wrapf(err, "unable to create ArrayObj for interface: %+v", vv)
 		}
+		if obj == nil {
+			return nil, nil
+		}
 		return obj, nil
 	default:
 		return nil, oops.Errorf("unable to create Object from interface: %+v", input)
@@ -89,61 +180,47 @@ func (o StringObj) Parse() ([][]string, error) {
 	}, nil
 }
 
-// BoolObj implements the Object interface for a bool value.
-type BoolObj struct {
+// NullObj implements the Object interface for a null value. The Val is the
+// token written in place of the null.
+type NullObj struct {
 	*Prefix
-	Val bool
+	Val string
 }
 
-// NewBoolObj returns a BoolObj for the given input bool.
-func NewBoolObj(prefix string, input bool) *BoolObj {
-	return &BoolObj{
+// NewNullObj returns a NullObj written as the given token.
+func NewNullObj(prefix string, token string) *NullObj {
+	return &NullObj{
 		NewPrefix(prefix),
-		input,
+		token,
 	}
 }
 
-// Parse returns the 2d slice of strings for the given BoolObj.
-func (o BoolObj) Parse() ([][]string, error) {
+// Parse returns the 2d slice of strings for the given NullObj.
+func (o NullObj) Parse() ([][]string, error) {
 	return [][]string{
 		{string(*o.Prefix)},
-		{strconv.FormatBool(o.Val)},
+		{o.Val},
 	}, nil
 }
 
-// NumberObj implements the Object interface for a numeric value.
-type NumberObj struct {
+// BoolObj implements the Object interface for a bool value.
+type BoolObj struct {
 	*Prefix
-	Val float64
+	Val bool
 }
 
-// NewNumberObj returns a NumberObj for the given input float.
-func NewNumberObj(prefix string, input float64) *NumberObj {
-	return &NumberObj{
+// NewBoolObj returns a BoolObj for the given input bool.
+func NewBoolObj(prefix string, input bool) *BoolObj {
+	return &BoolObj{
 		NewPrefix(prefix),
 		input,
 	}
 }
 
-// Parse returns the 2d slice of strings for the given FloatObj.
-//
-// We do a check to see if the value is actually an integer, and treat if so.
-// This check is necessary because the results of unmarshalling a JSON byte
-// array into a map[string]interface{} treats all numeric values as floats. We
-// want the string representation that we eventually write to a CSV file to have
-// numeric values appear accurately if they're integers. i.e. "345" not "345.0".
-func (o NumberObj) Parse() ([][]string, error) {
-	floatVal := o.Val
-
-	var stringVal string
-	if float64(int64(floatVal)) == floatVal {
-		stringVal = strconv.FormatInt(int64(floatVal), 10)
-	} else {
-		stringVal = strconv.FormatFloat(floatVal, 'f', -1, 64)
-	}
-
+// Parse returns the 2d slice of strings for the given BoolObj.
+func (o BoolObj) Parse() ([][]string, error) {
 	return [][]string{
 		{string(*o.Prefix)},
-		{stringVal},
+		{strconv.FormatBool(o.Val)},
 	}, nil
 }
@@ -88,6 +88,22 @@ func TestParseObject(t *testing.T) {
 				{"stringVal"},
 			},
 		},
+		{
+			description: "null",
+			input:       oo.NewNullObj("pref1", ""),
+			expected: [][]string{
+				{"pref1"},
+				{""},
+			},
+		},
+		{
+			description: "null with token",
+			input:       oo.NewNullObj("pref1", `\N`),
+			expected: [][]string{
+				{"pref1"},
+				{`\N`},
+			},
+		},
 		{
 			description: "simple bool",
 			input:       oo.NewBoolObj("pref1", true),
@@ -120,6 +136,8 @@ func TestParseObject(t *testing.T) {
 				map[string]oo.Object{
 					"key": oo.StringObj{oo.NewPrefix("pref_key"), "val"},
 				},
+				oo.SiblingRepeat,
+				oo.ZipPad,
 			},
 			expected: [][]string{
 				{"pref_key"},
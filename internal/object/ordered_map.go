@@ -0,0 +1,93 @@
+package object
+
+import (
+	"bytes"
+
+	"github.com/samsarahq/go/oops"
+)
+
+// KeyOrder describes the order of the columns built from the keys of a map.
+type KeyOrder int
+
+const (
+	// KeyOrderAlphabetical sorts the keys of every map alphabetically.
+	KeyOrderAlphabetical KeyOrder = iota
+	// KeyOrderDocument keeps the keys of every map in the order they're first
+	// seen in the document. This needs the maps to be given as OrderedMaps,
+	// the keys of a map[string]interface{} are sorted alphabetically since it
+	// doesn't have an order.
+	KeyOrderDocument
+)
+
+// String returns the name of the KeyOrder, as accepted by ParseKeyOrder.
+func (o KeyOrder) String() string {
+	switch o {
+	case KeyOrderAlphabetical:
+		return "alphabetical"
+	case KeyOrderDocument:
+		return "document"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseKeyOrder returns the KeyOrder with the given name, or an error if the
+// name doesn't match any KeyOrder.
+func ParseKeyOrder(name string) (KeyOrder, error) {
+	for _, o := range []KeyOrder{KeyOrderAlphabetical, KeyOrderDocument} {
+		if o.String() == name {
+			return o, nil
+		}
+	}
+	return KeyOrderAlphabetical, oops.Errorf("unknown key order: %s", name)
+}
+
+// OrderedMap is a representation of a JSON map that keeps track of the order
+// its keys appear in the document.
+type OrderedMap struct {
+	Keys   []string
+	Values map[string]interface{}
+}
+
+// NewOrderedMap returns a pointer to an empty OrderedMap.
+func NewOrderedMap() *OrderedMap {
+	return &OrderedMap{
+		Values: make(map[string]interface{}),
+	}
+}
+
+// Set sets the value for the given key. A key that's set more than once keeps
+// the position from the first time it was set, and the value from the last.
+func (m *OrderedMap) Set(key string, value interface{}) {
+	if _, ok := m.Values[key]; !ok {
+		m.Keys = append(m.Keys, key)
+	}
+	m.Values[key] = value
+}
+
+// MarshalJSON implements the json.Marshaler interface, the keys are written in
+// the order of the OrderedMap's Keys.
+func (m *OrderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range m.Keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		key, err := marshalJSON(k)
+		if err != nil {
+			return nil, oops.Wrapf(err, "unable to marshal key: %s", k)
+		}
+		val, err := marshalJSON(m.Values[k])
+		if err != nil {
+			return nil, oops.Wrapf(err, "unable to marshal value for key: %s", k)
+		}
+
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
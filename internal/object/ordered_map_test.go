@@ -0,0 +1,98 @@
+package object_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
+)
+
+func TestOrderedMapSet(t *testing.T) {
+	om := oo.NewOrderedMap()
+	om.Set("b", 1)
+	om.Set("a", 2)
+	om.Set("b", 3)
+
+	assert.Equal(t, []string{"b", "a"}, om.Keys)
+	assert.Equal(t, map[string]interface{}{"a": 2, "b": 3}, om.Values)
+}
+
+func TestKeyOrder(t *testing.T) {
+	nested := oo.NewOrderedMap()
+	nested.Set("z", "one")
+	nested.Set("y", "two")
+
+	input := oo.NewOrderedMap()
+	input.Set("id", nested)
+	input.Set("b", true)
+	input.Set("a", "three")
+
+	testcases := []struct {
+		description string
+		order       oo.KeyOrder
+		input       interface{}
+		expected    [][]string
+	}{
+		{
+			description: "alphabetical",
+			order:       oo.KeyOrderAlphabetical,
+			input:       input,
+			expected:    [][]string{{"a", "b", "id_y", "id_z"}, {"three", "true", "two", "one"}},
+		},
+		{
+			description: "document",
+			order:       oo.KeyOrderDocument,
+			input:       input,
+			expected:    [][]string{{"id_z", "id_y", "b", "a"}, {"one", "two", "true", "three"}},
+		},
+		{
+			description: "document order of an unordered map",
+			order:       oo.KeyOrderDocument,
+			input:       map[string]interface{}{"b": true, "a": "three"},
+			expected:    [][]string{{"a", "b"}, {"three", "true"}},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			cfg := oo.DefaultConfig()
+			cfg.KeyOrder = testcase.order
+
+			obj, err := cfg.FromInterface("", testcase.input)
+			assert.NoError(t, err)
+
+			parsed, err := obj.Parse()
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expected, parsed)
+		})
+	}
+}
+
+func TestParseKeyOrder(t *testing.T) {
+	for _, order := range []oo.KeyOrder{oo.KeyOrderAlphabetical, oo.KeyOrderDocument} {
+		parsed, err := oo.ParseKeyOrder(order.String())
+		assert.NoError(t, err)
+		assert.Equal(t, order, parsed)
+	}
+
+	_, err := oo.ParseKeyOrder("reverse")
+	assert.Error(t, err)
+}
+
+func TestOrderedMapMarshalJSON(t *testing.T) {
+	nested := oo.NewOrderedMap()
+	nested.Set("y", []interface{}{"<", nil})
+
+	om := oo.NewOrderedMap()
+	om.Set("b", nested)
+	om.Set("a", true)
+
+	b, err := om.MarshalJSON()
+	assert.NoError(t, err)
+	assert.Equal(t, `{"b":{"y":["<",null]},"a":true}`, string(b))
+
+	b, err = oo.NewOrderedMap().MarshalJSON()
+	assert.NoError(t, err)
+	assert.Equal(t, `{}`, string(b))
+}
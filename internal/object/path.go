@@ -0,0 +1,154 @@
+package object
+
+import (
+	"strings"
+
+	"github.com/samsarahq/go/oops"
+)
+
+// HeaderStyle describes how the keys on the path to a value are joined to build
+// the value's column header.
+type HeaderStyle int
+
+const (
+	// HeaderPlain joins the keys with the Config's Separator as they are. Keys
+	// that contain the Separator make the header ambiguous.
+	HeaderPlain HeaderStyle = iota
+	// HeaderEscaped joins the keys with the Config's Separator, and puts a
+	// backslash in front of any backslash or Separator inside a key, so every
+	// header maps back to exactly one path.
+	HeaderEscaped
+	// HeaderPointer builds headers as JSON Pointers, e.g. `/owner/user_id`, as
+	// defined by RFC 6901. The Config's Separator isn't used.
+	HeaderPointer
+)
+
+// String returns the name of the HeaderStyle, as accepted by ParseHeaderStyle.
+func (s HeaderStyle) String() string {
+	switch s {
+	case HeaderPlain:
+		return "plain"
+	case HeaderEscaped:
+		return "escaped"
+	case HeaderPointer:
+		return "pointer"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseHeaderStyle returns the HeaderStyle with the given name, or an error if
+// the name doesn't match any HeaderStyle.
+func ParseHeaderStyle(name string) (HeaderStyle, error) {
+	for _, s := range []HeaderStyle{HeaderPlain, HeaderEscaped, HeaderPointer} {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+	return HeaderPlain, oops.Errorf("unknown header style: %s", name)
+}
+
+// JoinHeader returns the column header for the value at the given path of keys,
+// built with the Config's HeaderStyle.
+func (c *Config) JoinHeader(path []string) string {
+	var header string
+	for _, key := range path {
+		header = c.joinPrefix(header, key)
+	}
+	return header
+}
+
+// SplitHeader returns the path of keys that the given column header was built
+// from with the Config's HeaderStyle. It's the inverse of JoinHeader, except
+// for HeaderPlain headers with keys that contain the Separator.
+func (c *Config) SplitHeader(header string) []string {
+	if header == "" {
+		return nil
+	}
+
+	switch c.HeaderStyle {
+	case HeaderEscaped:
+		return c.splitEscaped(header)
+	case HeaderPointer:
+		path, err := SplitPointer(header)
+		if err != nil {
+			return []string{header}
+		}
+		return path
+	default:
+		return strings.Split(header, c.Separator)
+	}
+}
+
+// joinPrefix returns the prefix for the value of the given key in a map with
+// the given prefix.
+func (c *Config) joinPrefix(prefix, key string) string {
+	switch c.HeaderStyle {
+	case HeaderEscaped:
+		key = c.escapeKey(key)
+	case HeaderPointer:
+		return prefix + "/" + EscapePointerToken(key)
+	}
+
+	if prefix == "" {
+		return key
+	}
+	return prefix + c.Separator + key
+}
+
+// escapeKey returns the given key with a backslash in front of every backslash
+// and every occurrence of the Config's Separator.
+func (c *Config) escapeKey(key string) string {
+	key = strings.ReplaceAll(key, `\`, `\\`)
+	return strings.ReplaceAll(key, c.Separator, `\`+c.Separator)
+}
+
+// splitEscaped splits a header built with HeaderEscaped into its keys.
+func (c *Config) splitEscaped(header string) []string {
+	var path []string
+	var key strings.Builder
+	for i := 0; i < len(header); {
+		switch {
+		case header[i] == '\\' && strings.HasPrefix(header[i+1:], c.Separator):
+			key.WriteString(c.Separator)
+			i += 1 + len(c.Separator)
+		case header[i] == '\\' && i+1 < len(header):
+			key.WriteByte(header[i+1])
+			i += 2
+		case strings.HasPrefix(header[i:], c.Separator):
+			path = append(path, key.String())
+			key.Reset()
+			i += len(c.Separator)
+		default:
+			key.WriteByte(header[i])
+			i++
+		}
+	}
+	return append(path, key.String())
+}
+
+// EscapePointerToken returns the given key escaped for use as a reference token
+// in a JSON Pointer.
+func EscapePointerToken(key string) string {
+	key = strings.ReplaceAll(key, "~", "~0")
+	return strings.ReplaceAll(key, "/", "~1")
+}
+
+// SplitPointer returns the unescaped reference tokens of the given JSON Pointer,
+// as defined by RFC 6901. An empty pointer refers to the whole document and has
+// no tokens.
+func SplitPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, oops.Errorf("JSON Pointer must start with a '/': %s", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		token = strings.ReplaceAll(token, "~1", "/")
+		tokens[i] = strings.ReplaceAll(token, "~0", "~")
+	}
+	return tokens, nil
+}
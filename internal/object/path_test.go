@@ -0,0 +1,170 @@
+package object_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
+)
+
+func TestHeaderStyles(t *testing.T) {
+	testcases := []struct {
+		description string
+		config      *oo.Config
+		path        []string
+		expected    string
+		split       []string
+	}{
+		{
+			description: "plain",
+			config:      &oo.Config{Separator: "_", HeaderStyle: oo.HeaderPlain},
+			path:        []string{"owner", "user_id"},
+			expected:    "owner_user_id",
+			split:       []string{"owner", "user", "id"},
+		},
+		{
+			description: "escaped",
+			config:      &oo.Config{Separator: "_", HeaderStyle: oo.HeaderEscaped},
+			path:        []string{"owner", "user_id"},
+			expected:    `owner_user\_id`,
+		},
+		{
+			description: "escaped with backslashes",
+			config:      &oo.Config{Separator: "_", HeaderStyle: oo.HeaderEscaped},
+			path:        []string{`a\`, `_b\_`, "c"},
+			expected:    `a\\_\_b\\\__c`,
+		},
+		{
+			description: "escaped with multi character separator",
+			config:      &oo.Config{Separator: "__", HeaderStyle: oo.HeaderEscaped},
+			path:        []string{"owner", "user__id", "x_y"},
+			expected:    `owner__user\__id__x_y`,
+		},
+		{
+			description: "escaped with dot separator",
+			config:      &oo.Config{Separator: ".", HeaderStyle: oo.HeaderEscaped},
+			path:        []string{"a.b", "c"},
+			expected:    `a\.b.c`,
+		},
+		{
+			description: "pointer",
+			config:      &oo.Config{HeaderStyle: oo.HeaderPointer},
+			path:        []string{"owner", "user/id", "a~b"},
+			expected:    "/owner/user~1id/a~0b",
+		},
+		{
+			description: "pointer with empty key",
+			config:      &oo.Config{HeaderStyle: oo.HeaderPointer},
+			path:        []string{"owner", ""},
+			expected:    "/owner/",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			header := testcase.config.JoinHeader(testcase.path)
+			assert.Equal(t, testcase.expected, header)
+
+			// Unless the header is ambiguous, splitting it gives back the path.
+			split := testcase.split
+			if split == nil {
+				split = testcase.path
+			}
+			assert.Equal(t, split, testcase.config.SplitHeader(header))
+		})
+	}
+}
+
+func TestHeaderStyleFromInterface(t *testing.T) {
+	input := map[string]interface{}{
+		"owner": map[string]interface{}{
+			"user_id": "val1",
+		},
+		"owner_user": map[string]interface{}{
+			"id": "val2",
+		},
+	}
+
+	cfg := &oo.Config{Separator: "_", HeaderStyle: oo.HeaderEscaped}
+	obj, err := cfg.FromInterface("", input)
+	assert.NoError(t, err)
+
+	parsed, err := obj.Parse()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{`owner_user\_id`, `owner\_user_id`},
+		{"val1", "val2"},
+	}, parsed)
+}
+
+func TestParseHeaderStyle(t *testing.T) {
+	for _, style := range []oo.HeaderStyle{oo.HeaderPlain, oo.HeaderEscaped, oo.HeaderPointer} {
+		actual, err := oo.ParseHeaderStyle(style.String())
+		assert.NoError(t, err)
+		assert.Equal(t, style, actual)
+	}
+
+	_, err := oo.ParseHeaderStyle("nonexistent")
+	assert.Error(t, err)
+}
+
+func TestSplitPointer(t *testing.T) {
+	testcases := []struct {
+		pointer     string
+		expected    []string
+		expectError bool
+	}{
+		{pointer: "", expected: nil},
+		{pointer: "/", expected: []string{""}},
+		{pointer: "/data/items/0", expected: []string{"data", "items", "0"}},
+		{pointer: "/a~1b/c~0d", expected: []string{"a/b", "c~d"}},
+		{pointer: "data", expectError: true},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.pointer, func(t *testing.T) {
+			actual, err := oo.SplitPointer(testcase.pointer)
+			assert.Equal(t, testcase.expectError, err != nil)
+			assert.Equal(t, testcase.expected, actual)
+		})
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	testcases := []struct {
+		description string
+		config      *oo.Config
+		expectError bool
+	}{
+		{
+			description: "default config",
+			config:      oo.DefaultConfig(),
+		},
+		{
+			description: "empty separator",
+			config:      &oo.Config{Separator: ""},
+			expectError: true,
+		},
+		{
+			description: "empty separator with pointer headers",
+			config:      &oo.Config{Separator: "", HeaderStyle: oo.HeaderPointer},
+		},
+		{
+			description: "backslash separator with escaped headers",
+			config:      &oo.Config{Separator: `\`, HeaderStyle: oo.HeaderEscaped},
+			expectError: true,
+		},
+		{
+			description: "number precision past the digit limit",
+			config:      &oo.Config{Separator: "_", NumberPrecision: oo.MaxFixedDigits + 1},
+			expectError: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			assert.Equal(t, testcase.expectError, testcase.config.Validate() != nil)
+		})
+	}
+}
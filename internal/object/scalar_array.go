@@ -0,0 +1,88 @@
+package object
+
+import (
+	"strings"
+
+	"github.com/samsarahq/go/oops"
+)
+
+// ScalarArrayMode describes how arrays whose elements are all scalar values are
+// written.
+type ScalarArrayMode int
+
+const (
+	// ScalarArrayExpand writes scalar arrays the same way as any other array,
+	// as set by the ArrayMode.
+	ScalarArrayExpand ScalarArrayMode = iota
+	// ScalarArrayJoin writes a scalar array in a single cell, with its
+	// elements joined by the ScalarArraySeparator, like `a|b`.
+	ScalarArrayJoin
+	// ScalarArrayJSON writes a scalar array in a single cell, as a JSON array
+	// literal, like `["a","b"]`.
+	ScalarArrayJSON
+)
+
+// String returns the name of the ScalarArrayMode, as accepted by
+// ParseScalarArrayMode.
+func (m ScalarArrayMode) String() string {
+	switch m {
+	case ScalarArrayExpand:
+		return "expand"
+	case ScalarArrayJoin:
+		return "join"
+	case ScalarArrayJSON:
+		return "json"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseScalarArrayMode returns the ScalarArrayMode with the given name, or an
+// error if the name doesn't match any ScalarArrayMode.
+func ParseScalarArrayMode(name string) (ScalarArrayMode, error) {
+	for _, m := range []ScalarArrayMode{ScalarArrayExpand, ScalarArrayJoin, ScalarArrayJSON} {
+		if m.String() == name {
+			return m, nil
+		}
+	}
+	return ScalarArrayExpand, oops.Errorf("unknown scalar array mode: %s", name)
+}
+
+// isScalarArray returns true if the given slice isn't empty, and none of its
+// elements are arrays or maps.
+func isScalarArray(input []interface{}) bool {
+	for _, v := range input {
+		if isContainer(v) {
+			return false
+		}
+	}
+	return len(input) > 0
+}
+
+// newScalarArrayObj returns a StringObj holding the given scalar array in a
+// single cell, according to the Config's ScalarArrayMode. The scope is the
+// array's scope, which its elements share.
+func (c *Config) newScalarArrayObj(prefix string, input []interface{}, s scope) (*StringObj, error) {
+	if c.ScalarArrayMode == ScalarArrayJSON {
+		encoded, err := encodeJSON(input)
+		if err != nil {
+			return nil, oops.Wrapf(err, "unable to encode scalar array: %+v", input)
+		}
+		return NewStringObj(prefix, encoded), nil
+	}
+
+	// Each element is written the same way it would be in a cell of its own.
+	cells := make([]string, len(input))
+	for i, v := range input {
+		obj, err := c.fromValue(prefix, v, s)
+		if err != nil {
+			return nil, oops.Wrapf(err, "unable to create Object for array element: %+v", v)
+		}
+		parsed, err := obj.Parse()
+		if err != nil {
+			return nil, oops.Wrapf(err, "unable to parse array element: %+v", v)
+		}
+		cells[i] = parsed[1][0]
+	}
+	return NewStringObj(prefix, strings.Join(cells, c.ScalarArraySeparator)), nil
+}
@@ -0,0 +1,82 @@
+package object_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
+)
+
+func TestScalarArrayMode(t *testing.T) {
+	input := map[string]interface{}{
+		"id":     "1",
+		"tags":   []interface{}{"a", "b<c>"},
+		"mixed":  []interface{}{"x", true, json.Number("1.50"), nil},
+		"events": []interface{}{map[string]interface{}{"at": "10"}, map[string]interface{}{"at": "20"}},
+	}
+
+	testcases := []struct {
+		description string
+		config      *oo.Config
+		expected    [][]string
+	}{
+		{
+			description: "expand follows the array mode",
+			config:      &oo.Config{Separator: "_", ScalarArrayMode: oo.ScalarArrayExpand, ArrayMode: oo.ArrayColumns},
+			expected: [][]string{
+				{"events_0_at", "events_1_at", "id", "mixed_0", "mixed_1", "mixed_2", "mixed_3", "tags_0", "tags_1"},
+				{"10", "20", "1", "x", "true", "1.50", "", "a", "b<c>"},
+			},
+		},
+		{
+			description: "join",
+			config:      &oo.Config{Separator: "_", ScalarArrayMode: oo.ScalarArrayJoin, ScalarArraySeparator: "|", NullToken: "NULL"},
+			expected: [][]string{
+				{"events_at", "id", "mixed", "tags"},
+				{"10", "1", "x|true|1.50|NULL", "a|b<c>"},
+				{"20", "1", "x|true|1.50|NULL", "a|b<c>"},
+			},
+		},
+		{
+			description: "json",
+			config:      &oo.Config{Separator: "_", ScalarArrayMode: oo.ScalarArrayJSON},
+			expected: [][]string{
+				{"events_at", "id", "mixed", "tags"},
+				{"10", "1", `["x",true,1.50,null]`, `["a","b<c>"]`},
+				{"20", "1", `["x",true,1.50,null]`, `["a","b<c>"]`},
+			},
+		},
+		{
+			description: "join with arrays of maps as columns",
+			config:      &oo.Config{Separator: "_", ScalarArrayMode: oo.ScalarArrayJoin, ScalarArraySeparator: ";", ArrayMode: oo.ArrayColumns},
+			expected: [][]string{
+				{"events_0_at", "events_1_at", "id", "mixed", "tags"},
+				{"10", "20", "1", "x;true;1.50;", "a;b<c>"},
+			},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			obj, err := testcase.config.FromInterface("", input)
+			assert.NoError(t, err)
+
+			parsed, err := obj.Parse()
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expected, parsed)
+		})
+	}
+}
+
+func TestParseScalarArrayMode(t *testing.T) {
+	for _, mode := range []oo.ScalarArrayMode{oo.ScalarArrayExpand, oo.ScalarArrayJoin, oo.ScalarArrayJSON} {
+		parsed, err := oo.ParseScalarArrayMode(mode.String())
+		assert.NoError(t, err)
+		assert.Equal(t, mode, parsed)
+	}
+
+	_, err := oo.ParseScalarArrayMode("csv")
+	assert.Error(t, err)
+}
@@ -0,0 +1,188 @@
+package object
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/samsarahq/go/oops"
+)
+
+// shape is the size of the 2d slice of strings an Object parses into: the
+// number of data rows and the header row, along with the source of each column.
+// A nil header means the Object parses into nothing at all.
+type shape struct {
+	rows    int
+	headers []string
+	sources []string
+}
+
+// pathStep is a step on the path from the root of the document to a value,
+// either a key of a map or an index of an array.
+type pathStep struct {
+	key   string
+	index bool
+}
+
+// checkFunc returns an error if the shape of the value at the given path is too
+// big.
+type checkFunc func(path []pathStep, s shape) error
+
+// noCheck is a checkFunc that accepts every shape.
+func noCheck(path []pathStep, s shape) error {
+	return nil
+}
+
+// Sources returns the path of the value in the input that each column of the
+// given Object comes from, in the same order as the header row returned by
+// Parse. The paths are written as JSONPath expressions, like `$.owner.user_id`,
+// with `[*]` for the elements of an array, since a column can hold values from
+// every element.
+//
+// Different paths can build the same header, like a key `a_b` and a key `b`
+// nested under `a`, and the sources tell those columns apart.
+func Sources(obj Object) ([]string, error) {
+	s, err := obj.shape(nil, noCheck)
+	if err != nil {
+		return nil, oops.Wrapf(err, "unable to find column sources")
+	}
+	return s.sources, nil
+}
+
+// shape returns the shape of a scalar value, a single cell under its prefix.
+func (p Prefix) shape(path []pathStep, check checkFunc) (shape, error) {
+	return shape{rows: 1, headers: []string{string(p)}, sources: []string{formatSource(path)}}, nil
+}
+
+// shape returns the shape of the EmptyObj, which is small enough to parse.
+func (o EmptyObj) shape(path []pathStep, check checkFunc) (shape, error) {
+	parsed, err := o.Parse()
+	if err != nil {
+		return shape{}, oops.Wrapf(err, "unable to parse EmptyObj")
+	}
+
+	ret := shape{rows: len(parsed) - 1, headers: parsed[0]}
+	for range parsed[0] {
+		ret.sources = append(ret.sources, formatSource(path))
+	}
+	return ret, nil
+}
+
+// shape returns the shape of the MapObj, combining the shapes of its values the
+// same way Parse combines their rows.
+func (o MapObj) shape(path []pathStep, check checkFunc) (shape, error) {
+	var ret shape
+	var rows []int
+	var repeats []bool
+
+	for _, key := range o.SortedKeys {
+		s, err := o.Val[key].shape(childPath(path, pathStep{key: key}), check)
+		if err != nil {
+			return shape{}, err
+		}
+		if s.headers == nil {
+			continue
+		}
+
+		if rows == nil {
+			ret.headers = []string{}
+		}
+		ret.headers = append(ret.headers, s.headers...)
+		ret.sources = append(ret.sources, s.sources...)
+		rows = append(rows, s.rows)
+		repeats = append(repeats, o.repeats(o.Val[key], s.rows))
+		ret.rows = combinedRows(rows, repeats, o.Siblings == SiblingZip && o.ZipPolicy == ZipTruncate)
+
+		if err := check(path, ret); err != nil {
+			return shape{}, err
+		}
+	}
+
+	return ret, nil
+}
+
+// shape returns the shape of the ArrayObj, combining the shapes of its elements
+// the same way MergeParsed combines their rows. A column's source is taken from
+// the first element that has the column.
+func (o ArrayObj) shape(path []pathStep, check checkFunc) (shape, error) {
+	var ret shape
+	count := make(map[string]int)
+
+	for i, item := range o.Val {
+		s, err := item.shape(childPath(path, pathStep{key: strconv.Itoa(i), index: true}), check)
+		if err != nil {
+			return shape{}, err
+		}
+		if s.headers == nil {
+			continue
+		}
+
+		seen := make(map[string]int)
+		for j, h := range s.headers {
+			seen[h]++
+			if seen[h] > count[h] {
+				count[h] = seen[h]
+				ret.headers = append(ret.headers, h)
+				ret.sources = append(ret.sources, s.sources[j])
+			}
+		}
+		ret.rows += s.rows
+
+		if ret.headers == nil {
+			continue
+		}
+		if err := check(path, ret); err != nil {
+			return shape{}, err
+		}
+	}
+
+	if ret.headers == nil {
+		return shape{}, nil
+	}
+	return ret, nil
+}
+
+// childPath returns the given path with the step appended, without sharing
+// storage with any other child of the same path.
+func childPath(path []pathStep, step pathStep) []pathStep {
+	return append(path[:len(path):len(path)], step)
+}
+
+// formatPointer returns the JSON Pointer for the given path, or `(root)` for
+// the root of the document, which is an empty pointer.
+func formatPointer(path []pathStep) string {
+	if len(path) == 0 {
+		return "(root)"
+	}
+	var sb strings.Builder
+	for _, step := range path {
+		sb.WriteString("/")
+		sb.WriteString(EscapePointerToken(step.key))
+	}
+	return sb.String()
+}
+
+// identifierRegexp matches the keys that can be written after a dot in a
+// JSONPath expression.
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// formatSource returns the JSONPath expression for the given path, with `[*]`
+// in place of every array index, or `$` for the root of the document.
+func formatSource(path []pathStep) string {
+	var sb strings.Builder
+	sb.WriteString("$")
+	for _, step := range path {
+		switch {
+		case step.index:
+			sb.WriteString("[*]")
+		case identifierRegexp.MatchString(step.key):
+			sb.WriteString(".")
+			sb.WriteString(step.key)
+		default:
+			sb.WriteString("['")
+			sb.WriteString(strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(step.key))
+			sb.WriteString("']")
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,84 @@
+package object_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
+)
+
+func TestSources(t *testing.T) {
+	testcases := []struct {
+		description string
+		scalars     oo.ScalarArrayMode
+		input       interface{}
+		expected    []string
+	}{
+		{
+			description: "scalar",
+			input:       "x",
+			expected:    []string{"$"},
+		},
+		{
+			description: "nested keys",
+			input: map[string]interface{}{
+				"a":   map[string]interface{}{"b": 1.0},
+				"a_b": 2.0,
+			},
+			expected: []string{"$.a.b", "$.a_b"},
+		},
+		{
+			description: "keys that need quoting",
+			input: map[string]interface{}{
+				"a b": map[string]interface{}{"it's": 1.0},
+			},
+			expected: []string{`$['a b']['it\'s']`},
+		},
+		{
+			description: "array elements share columns",
+			input: map[string]interface{}{
+				"events": []interface{}{
+					map[string]interface{}{"id": 1.0},
+					map[string]interface{}{"id": 2.0, "at": 3.0},
+				},
+			},
+			expected: []string{"$.events[*].id", "$.events[*].at"},
+		},
+		{
+			description: "empty values",
+			input: map[string]interface{}{
+				"a": map[string]interface{}{},
+				"b": []interface{}{},
+			},
+			expected: []string{"$.a", "$.b"},
+		},
+		{
+			description: "joined scalar array",
+			scalars:     oo.ScalarArrayJoin,
+			input: map[string]interface{}{
+				"tags": []interface{}{"a", "b"},
+			},
+			expected: []string{"$.tags"},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			cfg := oo.DefaultConfig()
+			cfg.ScalarArrayMode = testcase.scalars
+
+			obj, err := cfg.FromInterface("", testcase.input)
+			assert.NoError(t, err)
+
+			actual, err := oo.Sources(obj)
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expected, actual)
+
+			// There's a source for every column of the parsed header row.
+			parsed, err := obj.Parse()
+			assert.NoError(t, err)
+			assert.Len(t, actual, len(parsed[0]))
+		})
+	}
+}
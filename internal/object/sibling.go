@@ -0,0 +1,186 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samsarahq/go/oops"
+)
+
+// SiblingMode describes how the rows of sibling values in a map, like two
+// arrays under the same parent, are combined.
+type SiblingMode int
+
+const (
+	// SiblingRepeat lines up the rows of the siblings, and repeats the last
+	// row of a shorter sibling for the rest of the rows.
+	SiblingRepeat SiblingMode = iota
+	// SiblingZip pairs up sibling arrays element by element, so row i takes
+	// element i from each of them, and repeats scalar values in every row.
+	// Arrays of different lengths are handled by the ZipPolicy, and every
+	// element of a zipped array must fill a single row.
+	SiblingZip
+)
+
+// String returns the name of the SiblingMode, as accepted by ParseSiblingMode.
+func (m SiblingMode) String() string {
+	switch m {
+	case SiblingRepeat:
+		return "repeat"
+	case SiblingZip:
+		return "zip"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseSiblingMode returns the SiblingMode with the given name, or an error if
+// the name doesn't match any SiblingMode.
+func ParseSiblingMode(name string) (SiblingMode, error) {
+	for _, m := range []SiblingMode{SiblingRepeat, SiblingZip} {
+		if m.String() == name {
+			return m, nil
+		}
+	}
+	return SiblingRepeat, oops.Errorf("unknown sibling mode: %s", name)
+}
+
+// ZipPolicy describes how sibling arrays with different numbers of elements are
+// zipped together with SiblingZip. A scalar value is always repeated in every
+// row, but an array with a single element is zipped like any other array.
+type ZipPolicy int
+
+const (
+	// ZipPad writes as many rows as the longest array, with blank cells for
+	// the shorter arrays.
+	ZipPad ZipPolicy = iota
+	// ZipTruncate writes as many rows as the shortest array, and drops the
+	// rest of the longer arrays.
+	ZipTruncate
+	// ZipError fails if the sibling arrays have different numbers of
+	// elements.
+	ZipError
+)
+
+// String returns the name of the ZipPolicy, as accepted by ParseZipPolicy.
+func (p ZipPolicy) String() string {
+	switch p {
+	case ZipPad:
+		return "pad"
+	case ZipTruncate:
+		return "truncate"
+	case ZipError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseZipPolicy returns the ZipPolicy with the given name, or an error if the
+// name doesn't match any ZipPolicy.
+func ParseZipPolicy(name string) (ZipPolicy, error) {
+	for _, p := range []ZipPolicy{ZipPad, ZipTruncate, ZipError} {
+		if p.String() == name {
+			return p, nil
+		}
+	}
+	return ZipPad, oops.Errorf("unknown zip policy: %s", name)
+}
+
+// repeats returns true if the given value of the MapObj, which parses into the
+// given number of rows, has its single row repeated in every row of the map.
+// When zipping only scalar values, and maps of them, are repeated, and arrays
+// follow the ZipPolicy whatever their length.
+func (o MapObj) repeats(item Object, rows int) bool {
+	if rows != 1 {
+		return false
+	}
+	return o.Siblings == SiblingRepeat || !isArrayObj(item)
+}
+
+// isArrayObj returns true if the given Object is an ArrayObj.
+func isArrayObj(obj Object) bool {
+	switch obj.(type) {
+	case ArrayObj, *ArrayObj:
+		return true
+	default:
+		return false
+	}
+}
+
+// combinedRows returns the number of rows for siblings with the given numbers
+// of rows put side by side. Siblings that repeat their single row don't count.
+// Otherwise there are as many rows as the longest sibling has, or the shortest
+// one if shortest is true, and none if any sibling has no rows.
+func combinedRows(rows []int, repeats []bool, shortest bool) int {
+	n := -1
+	for i, r := range rows {
+		switch {
+		case r == 0:
+			return 0
+		case repeats[i]:
+		case n == -1:
+			n = r
+		case shortest && r < n:
+			n = r
+		case !shortest && r > n:
+			n = r
+		}
+	}
+	if n == -1 {
+		return 1
+	}
+	return n
+}
+
+// rowCount returns the number of rows of the MapObj, for values with the given
+// keys and numbers of rows, or an error if the values can't be zipped together
+// with the ZipError policy.
+func (o MapObj) rowCount(keys []string, rows []int, repeats []bool) (int, error) {
+	if o.Siblings != SiblingZip {
+		return combinedRows(rows, repeats, false), nil
+	}
+
+	n := combinedRows(rows, repeats, o.ZipPolicy == ZipTruncate)
+	if o.ZipPolicy == ZipError && n > 0 {
+		for i, r := range rows {
+			if !repeats[i] && r != n {
+				return 0, oops.Errorf("sibling arrays in %q have different lengths: %s", o.getPrefix(), describeRows(keys, rows, repeats))
+			}
+		}
+	}
+	return n, nil
+}
+
+// describeRows returns a description of the number of rows of each of the
+// given keys that doesn't repeat its single row.
+func describeRows(keys []string, rows []int, repeats []bool) string {
+	var parts []string
+	for i, key := range keys {
+		if !repeats[i] {
+			parts = append(parts, fmt.Sprintf("%s has %d", key, rows[i]))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseZipped returns the 2d slice of strings for the given ArrayObj, which is
+// zipped with its siblings so row i holds element i. Returns an error if any of
+// its elements doesn't fill exactly one row, like a map holding an array, since
+// its rows couldn't be lined up with the elements of the siblings.
+func parseZipped(o ArrayObj) ([][]string, error) {
+	parsed, err := o.parseElements()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, p := range parsed {
+		if rows := len(p) - 1; rows != 1 {
+			if rows < 0 {
+				rows = 0
+			}
+			return nil, oops.Errorf("element %d of %q fills %d rows, so it can't be zipped with its siblings element by element", i, o.getPrefix(), rows)
+		}
+	}
+	return MergeParsed(parsed...), nil
+}
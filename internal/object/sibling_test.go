@@ -0,0 +1,209 @@
+package object_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
+)
+
+func TestSiblingZip(t *testing.T) {
+	input := map[string]interface{}{
+		"id":         "7",
+		"timestamps": []interface{}{"1", "2", "3"},
+		"values":     []interface{}{"10", "20"},
+	}
+
+	testcases := []struct {
+		description string
+		input       map[string]interface{}
+		policy      oo.ZipPolicy
+		expected    [][]string
+		expectError string
+	}{
+		{
+			description: "same lengths",
+			input: map[string]interface{}{
+				"id":         "7",
+				"timestamps": []interface{}{"1", "2"},
+				"values":     []interface{}{"10", "20"},
+			},
+			policy: oo.ZipError,
+			expected: [][]string{
+				{"id", "timestamps", "values"},
+				{"7", "1", "10"},
+				{"7", "2", "20"},
+			},
+		},
+		{
+			description: "pad shorter arrays",
+			input:       input,
+			policy:      oo.ZipPad,
+			expected: [][]string{
+				{"id", "timestamps", "values"},
+				{"7", "1", "10"},
+				{"7", "2", "20"},
+				{"7", "3", ""},
+			},
+		},
+		{
+			description: "truncate longer arrays",
+			input:       input,
+			policy:      oo.ZipTruncate,
+			expected: [][]string{
+				{"id", "timestamps", "values"},
+				{"7", "1", "10"},
+				{"7", "2", "20"},
+			},
+		},
+		{
+			description: "expect error for different lengths",
+			input:       input,
+			policy:      oo.ZipError,
+			expectError: "timestamps has 3, values has 2",
+		},
+		{
+			description: "scalars and maps of scalars are repeated",
+			input: map[string]interface{}{
+				"a": map[string]interface{}{"b": "x"},
+				"c": []interface{}{"1", "2"},
+				"d": "y",
+			},
+			policy: oo.ZipError,
+			expected: [][]string{
+				{"a_b", "c", "d"},
+				{"x", "1", "y"},
+				{"x", "2", "y"},
+			},
+		},
+		{
+			description: "single element arrays are padded",
+			input: map[string]interface{}{
+				"t": []interface{}{"1", "2", "3"},
+				"v": []interface{}{"9"},
+			},
+			policy: oo.ZipPad,
+			expected: [][]string{
+				{"t", "v"},
+				{"1", "9"},
+				{"2", ""},
+				{"3", ""},
+			},
+		},
+		{
+			description: "truncate to a single element array",
+			input: map[string]interface{}{
+				"t": []interface{}{"1", "2", "3"},
+				"v": []interface{}{"9"},
+			},
+			policy: oo.ZipTruncate,
+			expected: [][]string{
+				{"t", "v"},
+				{"1", "9"},
+			},
+		},
+		{
+			description: "expect error for single element array of a different length",
+			input: map[string]interface{}{
+				"t": []interface{}{"1", "2", "3"},
+				"v": []interface{}{"9"},
+			},
+			policy:      oo.ZipError,
+			expectError: "t has 3, v has 1",
+		},
+		{
+			description: "expect error for an element that fills more than one row",
+			input: map[string]interface{}{
+				"t": []interface{}{
+					map[string]interface{}{"id": "a", "x": []interface{}{"1", "2"}},
+					map[string]interface{}{"id": "b", "x": []interface{}{"3"}},
+				},
+				"v": []interface{}{"10", "20"},
+			},
+			policy:      oo.ZipPad,
+			expectError: `element 0 of "t" fills 2 rows`,
+		},
+		{
+			description: "expect error naming the number of elements",
+			input: map[string]interface{}{
+				"t": []interface{}{
+					map[string]interface{}{"id": "a"},
+					map[string]interface{}{"id": "b"},
+				},
+				"v": []interface{}{"10", "20", "30"},
+			},
+			policy:      oo.ZipError,
+			expectError: "t has 2, v has 3",
+		},
+		{
+			description: "arrays of maps",
+			input: map[string]interface{}{
+				"a": []interface{}{
+					map[string]interface{}{"b": "1", "c": "2"},
+					map[string]interface{}{"b": "3"},
+				},
+				"d": []interface{}{"x", "y", "z"},
+			},
+			policy: oo.ZipPad,
+			expected: [][]string{
+				{"a_b", "a_c", "d"},
+				{"1", "2", "x"},
+				{"3", "", "y"},
+				{"", "", "z"},
+			},
+		},
+		{
+			description: "empty array drops the rows",
+			input: map[string]interface{}{
+				"a": []interface{}{},
+				"b": []interface{}{"1", "2"},
+			},
+			policy: oo.ZipPad,
+			expected: [][]string{
+				{"a", "b"},
+				{"", "1"},
+				{"", "2"},
+			},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			config := oo.DefaultConfig()
+			config.SiblingMode = oo.SiblingZip
+			config.ZipPolicy = testcase.policy
+
+			obj, err := config.NewMapObj("", testcase.input)
+			assert.NoError(t, err)
+
+			actual, err := obj.Parse()
+			if testcase.expectError != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), testcase.expectError)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expected, actual)
+		})
+	}
+}
+
+func TestParseSiblingMode(t *testing.T) {
+	for _, mode := range []oo.SiblingMode{oo.SiblingRepeat, oo.SiblingZip} {
+		actual, err := oo.ParseSiblingMode(mode.String())
+		assert.NoError(t, err)
+		assert.Equal(t, mode, actual)
+	}
+	for _, policy := range []oo.ZipPolicy{oo.ZipPad, oo.ZipTruncate, oo.ZipError} {
+		actual, err := oo.ParseZipPolicy(policy.String())
+		assert.NoError(t, err)
+		assert.Equal(t, policy, actual)
+	}
+
+	_, err := oo.ParseSiblingMode("cross")
+	assert.Error(t, err)
+	_, err = oo.ParseZipPolicy("longest")
+	assert.Error(t, err)
+}
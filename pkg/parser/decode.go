@@ -0,0 +1,120 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/samsarahq/go/oops"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
+)
+
+// unmarshal returns the decoded representation of the given JSON data. Numbers
+// are decoded as json.Numbers so no precision is lost. If ordered is true then
+// maps are decoded as OrderedMaps, otherwise as map[string]interface{}.
+func unmarshal(data []byte, ordered bool) (interface{}, error) {
+	dec := newDecoder(bytes.NewReader(data))
+	result, err := decodeValue(dec, ordered)
+	if err != nil {
+		return nil, err
+	}
+
+	// Like json.Unmarshal, only accept a single value.
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, oops.Errorf("invalid data after top-level value at offset %d", dec.InputOffset())
+	}
+	return result, nil
+}
+
+// newDecoder returns a json.Decoder reading from the given io.Reader that
+// decodes numbers as json.Numbers.
+func newDecoder(r io.Reader) *json.Decoder {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	return dec
+}
+
+// decodeValue reads the next value from the given decoder. If ordered is true
+// then maps are decoded as OrderedMaps, otherwise as map[string]interface{}.
+func decodeValue(dec *json.Decoder, ordered bool) (interface{}, error) {
+	if !ordered {
+		var val interface{}
+		if err := dec.Decode(&val); err != nil {
+			return nil, err
+		}
+		return val, nil
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	return readValue(dec, tok, true)
+}
+
+// readValue reads the value that starts with the given token from the decoder.
+// If ordered is true then maps are decoded as OrderedMaps, otherwise as
+// map[string]interface{}.
+func readValue(dec *json.Decoder, tok json.Token, ordered bool) (interface{}, error) {
+	switch tok {
+	case json.Delim('{'):
+		om := oo.NewOrderedMap()
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, oops.Wrapf(err, "unable to read key")
+			}
+			val, err := decodeValue(dec, ordered)
+			if err != nil {
+				return nil, oops.Wrapf(err, "unable to decode value for key: %v", key)
+			}
+			om.Set(key.(string), val)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, oops.Wrapf(err, "unable to read end of object")
+		}
+		if ordered {
+			return om, nil
+		}
+		return om.Values, nil
+	case json.Delim('['):
+		ret := []interface{}{}
+		for dec.More() {
+			val, err := decodeValue(dec, ordered)
+			if err != nil {
+				return nil, oops.Wrapf(err, "unable to decode array element")
+			}
+			ret = append(ret, val)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, oops.Wrapf(err, "unable to read end of array")
+		}
+		return ret, nil
+	default:
+		return tok, nil
+	}
+}
+
+// skipValue reads the next value from the given decoder and throws it away. It
+// only holds one token in memory at a time.
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return oops.Wrapf(err, "unable to read token")
+		}
+
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+
+		if depth == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samsarahq/go/oops"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
+)
+
+// DuplicateHeaderPolicy describes what happens when two columns of the output
+// end up with the same header. This happens when different paths build the same
+// header, like a key `a_b` and a key `b` nested under `a`, or when truncating
+// the headers shortens two of them to the same string.
+type DuplicateHeaderPolicy int
+
+const (
+	// DuplicateError fails the conversion, naming the paths in the input that
+	// the conflicting columns come from.
+	DuplicateError DuplicateHeaderPolicy = iota
+	// DuplicateSuffix keeps the first column with a header as it is, and adds
+	// a number to the header of each later one, like `a_b_2` and `a_b_3`.
+	DuplicateSuffix
+	// DuplicatePath replaces the header of every conflicting column with the
+	// JSONPath of the value it comes from, like `$.a.b` and `$.a_b`.
+	DuplicatePath
+)
+
+// String returns the name of the DuplicateHeaderPolicy, as accepted by
+// ParseDuplicateHeaderPolicy.
+func (p DuplicateHeaderPolicy) String() string {
+	switch p {
+	case DuplicateError:
+		return "error"
+	case DuplicateSuffix:
+		return "suffix"
+	case DuplicatePath:
+		return "path"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseDuplicateHeaderPolicy returns the DuplicateHeaderPolicy with the given
+// name, or an error if the name doesn't match any DuplicateHeaderPolicy.
+func ParseDuplicateHeaderPolicy(name string) (DuplicateHeaderPolicy, error) {
+	for _, p := range []DuplicateHeaderPolicy{DuplicateError, DuplicateSuffix, DuplicatePath} {
+		if p.String() == name {
+			return p, nil
+		}
+	}
+	return DuplicateError, oops.Errorf("unknown duplicate header policy: %s", name)
+}
+
+// dedupe returns the given headers, which are the final headers about to be
+// written, with any duplicates handled by the options' DuplicateHeaderPolicy.
+// Returns an error if a header is repeated and the policy is DuplicateError.
+//
+// The sources of the columns are returned by the given function, which is only
+// called if they're needed.
+func (o options) dedupe(headers []string, sourcesFunc func() ([]string, error)) ([]string, error) {
+	groups := duplicateHeaders(headers)
+	if len(groups) == 0 {
+		return headers, nil
+	}
+	if o.duplicateHeaders == DuplicateSuffix {
+		return suffixHeaders(headers), nil
+	}
+
+	sources, err := columnSources(headers, sourcesFunc)
+	if err != nil {
+		return nil, err
+	}
+
+	switch o.duplicateHeaders {
+	case DuplicatePath:
+		ret := append([]string(nil), headers...)
+		for _, group := range groups {
+			for _, i := range group {
+				ret[i] = sources[i]
+			}
+		}
+		// Columns built from the same path, like a key inside an array of arrays,
+		// are still told apart by a suffix.
+		return suffixHeaders(ret), nil
+	default:
+		return nil, oops.Errorf("duplicate column headers: %s", describeConflicts(headers, sources, groups))
+	}
+}
+
+// objectSources returns a function that returns the sources of the columns of
+// the given Object, see oo.Sources.
+func objectSources(obj oo.Object) func() ([]string, error) {
+	return func() ([]string, error) {
+		return oo.Sources(obj)
+	}
+}
+
+// columnSources returns the sources returned by the given function, or an error
+// if there isn't one for each of the given headers.
+func columnSources(headers []string, sourcesFunc func() ([]string, error)) ([]string, error) {
+	sources, err := sourcesFunc()
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) != len(headers) {
+		return nil, oops.Errorf("found %d column sources for %d headers", len(sources), len(headers))
+	}
+	return sources, nil
+}
+
+// describeConflicts returns a description of each of the given groups of
+// columns that share a header, naming the header and the source of each column.
+func describeConflicts(headers, sources []string, groups [][]int) string {
+	var conflicts []string
+	for _, group := range groups {
+		paths := make([]string, len(group))
+		for j, i := range group {
+			paths[j] = sources[i]
+		}
+		conflicts = append(conflicts, fmt.Sprintf("%q from %s", headers[group[0]], strings.Join(paths, ", ")))
+	}
+	return strings.Join(conflicts, "; ")
+}
+
+// duplicateHeaders returns the indexes of the headers that share a header with
+// another one, grouped by header, in the order each header first appears.
+func duplicateHeaders(headers []string) [][]int {
+	indexes := make(map[string][]int)
+	var order []string
+	for i, h := range headers {
+		if _, ok := indexes[h]; !ok {
+			order = append(order, h)
+		}
+		indexes[h] = append(indexes[h], i)
+	}
+
+	var ret [][]int
+	for _, h := range order {
+		if len(indexes[h]) > 1 {
+			ret = append(ret, indexes[h])
+		}
+	}
+	return ret
+}
+
+// suffixHeaders returns the given headers with a number added to each repeat of
+// a header, skipping any number that would give a header already in use.
+func suffixHeaders(headers []string) []string {
+	used := make(map[string]bool)
+	for _, h := range headers {
+		used[h] = true
+	}
+
+	ret := make([]string, len(headers))
+	seen := make(map[string]bool)
+	for i, h := range headers {
+		ret[i] = h
+		if !seen[h] {
+			seen[h] = true
+			continue
+		}
+		for n := 2; ; n++ {
+			candidate := fmt.Sprintf("%s_%d", h, n)
+			if !used[candidate] {
+				used[candidate] = true
+				ret[i] = candidate
+				break
+			}
+		}
+	}
+	return ret
+}
@@ -0,0 +1,107 @@
+package parser_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ecshreve/jcgo/pkg/parser"
+)
+
+func TestConvertDuplicateHeaders(t *testing.T) {
+	testcases := []struct {
+		description string
+		input       string
+		opts        []parser.Option
+		expected    string
+		expectError string
+	}{
+		{
+			description: "expect error naming the sources",
+			input:       `{"a": {"b": 1}, "a_b": 2, "c": 3}`,
+			expectError: `duplicate column headers: "a_b" from $.a.b, $.a_b`,
+		},
+		{
+			description: "expect error naming the sources of every conflict",
+			input:       `[{"x": {"id": 1}, "x_id": 2, "y": {"id": 3}, "y_id": 4}]`,
+			expectError: `"x_id" from $[*].x.id, $[*].x_id; "y_id" from $[*].y.id, $[*].y_id`,
+		},
+		{
+			description: "expect error naming the truncated header",
+			input:       `{"data": {"x": {"a": {"b": 1}, "a_b": 2}, "y": 3}}`,
+			opts:        []parser.Option{parser.WithTruncateHeaders(true)},
+			expectError: `"x_a_b" from $.data.x.a.b, $.data.x.a_b`,
+		},
+		{
+			description: "suffix",
+			input:       `{"a": {"b": 1}, "a_b": 2, "c": 3}`,
+			opts:        []parser.Option{parser.WithDuplicateHeaders(parser.DuplicateSuffix)},
+			expected:    "a_b,a_b_2,c\n1,2,3\n",
+		},
+		{
+			description: "suffix skips headers already in use",
+			input:       `{"a": {"b": 1, "b_2": 2}, "a_b": 3}`,
+			opts: []parser.Option{
+				parser.WithDuplicateHeaders(parser.DuplicateSuffix),
+				parser.WithTruncateHeaders(false),
+			},
+			expected: "a_b,a_b_2,a_b_3\n1,2,3\n",
+		},
+		{
+			description: "path",
+			input:       `{"a": {"b": 1}, "a_b": 2, "c": 3}`,
+			opts:        []parser.Option{parser.WithDuplicateHeaders(parser.DuplicatePath)},
+			expected:    "$.a.b,$.a_b,c\n1,2,3\n",
+		},
+		{
+			description: "path with keys that need quoting",
+			input:       `{"a": {"b c": 1}, "a_b c": 2}`,
+			opts:        []parser.Option{parser.WithDuplicateHeaders(parser.DuplicatePath)},
+			expected:    "$.a['b c'],$['a_b c']\n1,2\n",
+		},
+		{
+			description: "suffix when streaming",
+			input:       `[{"a": {"b": 1}, "a_b": 2}, {"a_b": 4, "a": {"b": 3}}]`,
+			opts: []parser.Option{
+				parser.WithDuplicateHeaders(parser.DuplicateSuffix),
+				parser.WithStreaming(true),
+			},
+			expected: "a_b,a_b_2\n1,2\n3,4\n",
+		},
+		{
+			description: "expect error when streaming",
+			input:       `[{"a": {"b": 1}, "a_b": 2}]`,
+			opts:        []parser.Option{parser.WithStreaming(true)},
+			expectError: `"a_b" from $.a.b, $.a_b`,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := parser.Convert(context.Background(), strings.NewReader(testcase.input), &buf, testcase.opts...)
+			if testcase.expectError != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), testcase.expectError)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expected, buf.String())
+		})
+	}
+}
+
+func TestParseDuplicateHeaderPolicy(t *testing.T) {
+	for _, p := range []parser.DuplicateHeaderPolicy{parser.DuplicateError, parser.DuplicateSuffix, parser.DuplicatePath} {
+		actual, err := parser.ParseDuplicateHeaderPolicy(p.String())
+		assert.NoError(t, err)
+		assert.Equal(t, p, actual)
+	}
+
+	_, err := parser.ParseDuplicateHeaderPolicy("rename")
+	assert.Error(t, err)
+}
@@ -0,0 +1,125 @@
+package parser
+
+import (
+	"path/filepath"
+	"unicode/utf8"
+
+	"github.com/samsarahq/go/oops"
+)
+
+// InputFormat describes how the input to a Parser is laid out.
+type InputFormat int
+
+const (
+	// FormatAuto picks the input format based on the input file extension.
+	FormatAuto InputFormat = iota
+	// FormatJSON is a single JSON document.
+	FormatJSON
+	// FormatNDJSON is newline delimited JSON, one JSON value per line.
+	FormatNDJSON
+)
+
+// String returns the name of the InputFormat, as accepted by ParseInputFormat.
+func (f InputFormat) String() string {
+	switch f {
+	case FormatAuto:
+		return "auto"
+	case FormatJSON:
+		return "json"
+	case FormatNDJSON:
+		return "ndjson"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseInputFormat returns the InputFormat with the given name, or an error if
+// the name doesn't match any InputFormat.
+func ParseInputFormat(name string) (InputFormat, error) {
+	for _, f := range []InputFormat{FormatAuto, FormatJSON, FormatNDJSON} {
+		if f.String() == name {
+			return f, nil
+		}
+	}
+	return FormatAuto, oops.Errorf("unknown input format: %s", name)
+}
+
+// DetectInputFormat returns the InputFormat implied by the extension of the
+// file at the given path. Files ending in `.jsonl` or `.ndjson` are NDJSON,
+// everything else is treated as a single JSON document.
+func DetectInputFormat(path string) InputFormat {
+	switch filepath.Ext(path) {
+	case ".jsonl", ".ndjson":
+		return FormatNDJSON
+	default:
+		return FormatJSON
+	}
+}
+
+// OutputFormat describes how the output of a Parser is laid out.
+type OutputFormat int
+
+const (
+	// FormatCSV is comma separated values.
+	FormatCSV OutputFormat = iota
+	// FormatTSV is tab separated values.
+	FormatTSV
+)
+
+// String returns the name of the OutputFormat, as accepted by
+// ParseOutputFormat.
+func (f OutputFormat) String() string {
+	switch f {
+	case FormatCSV:
+		return "csv"
+	case FormatTSV:
+		return "tsv"
+	default:
+		return "unknown"
+	}
+}
+
+// Extension returns the file extension for files of the OutputFormat.
+func (f OutputFormat) Extension() string {
+	return "." + f.String()
+}
+
+// Delimiter returns the field delimiter used by the OutputFormat.
+func (f OutputFormat) Delimiter() rune {
+	if f == FormatTSV {
+		return '\t'
+	}
+	return ','
+}
+
+// ParseOutputFormat returns the OutputFormat with the given name, or an error if
+// the name doesn't match any OutputFormat.
+func ParseOutputFormat(name string) (OutputFormat, error) {
+	for _, f := range []OutputFormat{FormatCSV, FormatTSV} {
+		if f.String() == name {
+			return f, nil
+		}
+	}
+	return FormatCSV, oops.Errorf("unknown output format: %s", name)
+}
+
+// ParseDelimiter returns the field delimiter described by the given string, or
+// an error if it isn't a valid delimiter. The string must be a single
+// character, or `\t` for a tab.
+func ParseDelimiter(s string) (rune, error) {
+	if s == `\t` {
+		return '\t', nil
+	}
+
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 || size != len(s) || !validDelimiter(r) {
+		return 0, oops.Errorf("delimiter must be a single character other than a quote or newline: %q", s)
+	}
+	return r, nil
+}
+
+// validDelimiter returns true if the given rune can be used as a field
+// delimiter by a csv.Writer.
+func validDelimiter(r rune) bool {
+	return r != 0 && r != '"' && r != '\r' && r != '\n' && utf8.ValidRune(r) && r != utf8.RuneError
+}
@@ -0,0 +1,95 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ecshreve/jcgo/pkg/parser"
+)
+
+func TestParseInputFormat(t *testing.T) {
+	testcases := []struct {
+		input       string
+		expected    parser.InputFormat
+		expectError bool
+	}{
+		{input: "auto", expected: parser.FormatAuto},
+		{input: "json", expected: parser.FormatJSON},
+		{input: "ndjson", expected: parser.FormatNDJSON},
+		{input: "xml", expected: parser.FormatAuto, expectError: true},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.input, func(t *testing.T) {
+			actual, err := parser.ParseInputFormat(testcase.input)
+			assert.Equal(t, testcase.expectError, err != nil)
+			assert.Equal(t, testcase.expected, actual)
+		})
+	}
+}
+
+func TestDetectInputFormat(t *testing.T) {
+	testcases := []struct {
+		path     string
+		expected parser.InputFormat
+	}{
+		{path: "data.json", expected: parser.FormatJSON},
+		{path: "data.jsonl", expected: parser.FormatNDJSON},
+		{path: "data.ndjson", expected: parser.FormatNDJSON},
+		{path: "data", expected: parser.FormatJSON},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.path, func(t *testing.T) {
+			assert.Equal(t, testcase.expected, parser.DetectInputFormat(testcase.path))
+		})
+	}
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	testcases := []struct {
+		input       string
+		expected    parser.OutputFormat
+		expectError bool
+	}{
+		{input: "csv", expected: parser.FormatCSV},
+		{input: "tsv", expected: parser.FormatTSV},
+		{input: "xls", expected: parser.FormatCSV, expectError: true},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.input, func(t *testing.T) {
+			actual, err := parser.ParseOutputFormat(testcase.input)
+			assert.Equal(t, testcase.expectError, err != nil)
+			assert.Equal(t, testcase.expected, actual)
+		})
+	}
+}
+
+func TestParseDelimiter(t *testing.T) {
+	testcases := []struct {
+		input       string
+		expected    rune
+		expectError bool
+	}{
+		{input: ",", expected: ','},
+		{input: ";", expected: ';'},
+		{input: "|", expected: '|'},
+		{input: `\t`, expected: '\t'},
+		{input: "\t", expected: '\t'},
+		{input: "é", expected: 'é'},
+		{input: "", expectError: true},
+		{input: ",,", expectError: true},
+		{input: `"`, expectError: true},
+		{input: "\n", expectError: true},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.input, func(t *testing.T) {
+			actual, err := parser.ParseDelimiter(testcase.input)
+			assert.Equal(t, testcase.expectError, err != nil)
+			assert.Equal(t, testcase.expected, actual)
+		})
+	}
+}
@@ -1,9 +1,11 @@
 package parser
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/csv"
-	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -12,83 +14,282 @@ import (
 	"time"
 
 	"github.com/samsarahq/go/oops"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
 )
 
-// ReadJSONFile returns a pointer to the map representation of the JSON file at
-// the given path or an error if reading the JSON file was unsuccessful.
-func ReadJSONFile(path string) (*map[string]interface{}, error) {
+// StdioPath is the path used for input and output files to read from stdin or
+// write to stdout instead.
+const StdioPath = "-"
+
+// ReadJSONFile returns the decoded representation of the JSON file at the given
+// path or an error if reading the JSON file was unsuccessful. If the path is
+// StdioPath then the JSON is read from stdin.
+//
+// The root of the document can be any JSON value, so the result is one of the
+// types produced by json.Unmarshal for an interface{}: a map[string]interface{}
+// for an object, a []interface{} for an array, or a scalar value. Numbers are
+// decoded as json.Numbers so no precision is lost.
+func ReadJSONFile(path string) (interface{}, error) {
 	// Check that the given file is a JSON file.
 	ext := filepath.Ext(path)
-	if ext != ".json" {
+	if path != StdioPath && ext != ".json" {
 		return nil, oops.Errorf("input file must be a JSON file: %s", path)
 	}
 
 	// Open the file specified by path.
-	file, err := os.Open(path)
+	file, err := openInfile(path)
+	if err != nil {
+		return nil, oops.Wrapf(err, "unable to open file %s", path)
+	}
+	defer file.Close()
+
+	result, err := ReadJSON(file)
+	if err != nil {
+		return nil, oops.Wrapf(err, "unable to read JSON from file %s", path)
+	}
+
+	return result, nil
+}
+
+// ReadJSON returns the decoded representation of the JSON read from the given
+// io.Reader, or an error if reading the JSON was unsuccessful.
+func ReadJSON(r io.Reader) (interface{}, error) {
+	return readJSON(r, false)
+}
+
+// readJSON returns the decoded representation of the JSON read from the given
+// io.Reader, or an error if reading the JSON was unsuccessful. If ordered is
+// true then maps are decoded as OrderedMaps.
+func readJSON(r io.Reader, ordered bool) (interface{}, error) {
+	// Read everything into a byte array.
+	byteValue, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, oops.Wrapf(err, "unable to read input to byte array")
+	}
+
+	// Unmarshall the byte array into an interface so any root value is valid.
+	result, err := unmarshal(byteValue, ordered)
+	if err != nil {
+		return nil, oops.Wrapf(err, "unable to unmarshal byte array")
+	}
+
+	return result, nil
+}
+
+// ReadJSONLinesFile returns a slice with the decoded value of each line in the
+// newline delimited JSON (NDJSON) file at the given path, or an error if reading
+// the file was unsuccessful. If the path is StdioPath then the lines are read
+// from stdin.
+//
+// The file must have a `.jsonl` or `.ndjson` extension.
+func ReadJSONLinesFile(path string) ([]interface{}, error) {
+	// Check that the given file is a JSON Lines file.
+	if path != StdioPath && DetectInputFormat(path) != FormatNDJSON {
+		return nil, oops.Errorf("input file must be a JSON Lines file: %s", path)
+	}
+
+	// Open the file specified by path.
+	file, err := openInfile(path)
 	if err != nil {
 		return nil, oops.Wrapf(err, "unable to open file %s", path)
 	}
 	defer file.Close()
 
-	// Read the file into a byte array.
-	byteValue, err := ioutil.ReadAll(file)
+	result, err := ReadJSONLines(file)
 	if err != nil {
-		return nil, oops.Wrapf(err, "unable to read file %s to byte array", file.Name())
+		return nil, oops.Wrapf(err, "unable to read JSON lines from file %s", path)
 	}
 
-	// Unmarshall the byte array into a map.
-	var result map[string]interface{}
-	if err = json.Unmarshal([]byte(byteValue), &result); err != nil {
-		return nil, oops.Wrapf(err, "unable to unmarshal byte array to map")
+	return result, nil
+}
+
+// ReadJSONLines returns a slice with the decoded value of each line read from
+// the given io.Reader, or an error if reading the lines was unsuccessful.
+//
+// Blank lines are skipped.
+func ReadJSONLines(r io.Reader) ([]interface{}, error) {
+	return readJSONLines(r, false)
+}
+
+// readJSONLines returns a slice with the decoded value of each line read from
+// the given io.Reader, or an error if reading the lines was unsuccessful. If
+// ordered is true then maps are decoded as OrderedMaps.
+func readJSONLines(r io.Reader, ordered bool) ([]interface{}, error) {
+	result := []interface{}{}
+	lines := newJSONLines(r, ordered)
+	for {
+		value, err := lines.Next()
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
 	}
+}
+
+// jsonLines reads the values of NDJSON input one line at a time.
+type jsonLines struct {
+	reader  *bufio.Reader
+	ordered bool
+	lineNum int
+	done    bool
+}
+
+// newJSONLines returns a jsonLines reading from the given io.Reader. If ordered
+// is true then maps are decoded as OrderedMaps.
+func newJSONLines(r io.Reader, ordered bool) *jsonLines {
+	return &jsonLines{
+		reader:  bufio.NewReader(r),
+		ordered: ordered,
+	}
+}
 
-	return &result, nil
+// Next returns the decoded value of the next non-blank line, or io.EOF once
+// every line has been read. Returns an error if a line isn't a single JSON
+// value.
+func (l *jsonLines) Next() (interface{}, error) {
+	for !l.done {
+		line, err := l.reader.ReadBytes('\n')
+		l.lineNum++
+		if err != nil && err != io.EOF {
+			return nil, oops.Wrapf(err, "unable to read line %d", l.lineNum)
+		}
+		l.done = err == io.EOF
+
+		// Unmarshal each non-empty line on its own.
+		if trimmed := bytes.TrimSpace(line); len(trimmed) > 0 {
+			value, err := unmarshal(trimmed, l.ordered)
+			if err != nil {
+				return nil, oops.Wrapf(err, "unable to unmarshal line %d", l.lineNum)
+			}
+			return value, nil
+		}
+	}
+	return nil, io.EOF
 }
 
 // WriteCSVFile writes the given 2d slice of strings to a CSV file at the given
 // path. It returns a pointer to the output file, or an error if unsuccessful.
 //
-// If no path is provided, then a default filename is generated. This function
-// treats the first row in the data argument as the headers for  the CSV file.
+// If no path is provided, then a default filename is generated. If the path is
+// StdioPath, then the data is written to stdout. This function treats the first
+// row in the data argument as the headers for  the CSV file.
 func WriteCSVFile(data [][]string, path *string) (*os.File, error) {
-	var outfilePath *string
-	// If no path is provided create a default output filename.
-	if path == nil {
-		outfilePath = GetDefaultOutfilePath()
-	} else {
-		// Check that the given output file is a CSV file.
-		ext := filepath.Ext(*path)
-		if ext != ".csv" {
-			return nil, oops.Errorf("output file must be a CSV file: %s", *path)
-		}
-		outfilePath = path
+	file, err := createOutfile(path, FormatCSV)
+	if err != nil {
+		return nil, oops.Wrapf(err, "unable to create csv file")
 	}
+	defer closeOutfile(file)
 
-	// Create the output file.
-	file, err := os.Create(*outfilePath)
-	if err != nil {
-		return nil, oops.Wrapf(err, "unable to create output file for path: %s", *outfilePath)
+	// Write the data to the file.
+	if err := WriteCSV(data, file); err != nil {
+		return nil, oops.Wrapf(err, "unable to write data to file: %s", file.Name())
 	}
-	defer file.Close()
 
+	return file, nil
+}
+
+// WriteCSV writes the given 2d slice of strings as CSV to the given io.Writer,
+// and returns an error if unsuccessful.
+func WriteCSV(data [][]string, w io.Writer) error {
+	return writeDelimited(data, w, ',')
+}
+
+// writeDelimited writes the given 2d slice of strings to the given io.Writer
+// with the given field delimiter, and returns an error if unsuccessful.
+func writeDelimited(data [][]string, w io.Writer, comma rune) error {
 	// Create a CSV writer.
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
 
-	// Write each row in the data to a CSV file at the given path.
+	// Write each row in the data.
 	for _, value := range data {
 		err := writer.Write(value)
 		if err != nil {
-			return nil, oops.Wrapf(err, "unable to write value: %+v to file: %s", value, file.Name())
+			return oops.Wrapf(err, "unable to write value: %+v", value)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return oops.Wrapf(err, "unable to flush csv writer")
+	}
+	return nil
+}
+
+// CreateCSVFile creates a CSV file at the given path and returns a pointer to
+// it, or an error if unsuccessful. The caller is responsible for closing it.
+//
+// If no path is provided, then a default filename is generated.
+func CreateCSVFile(path *string) (*os.File, error) {
+	return createFile(path, FormatCSV.Extension())
+}
+
+// createFile creates a file with the given extension at the given path and
+// returns a pointer to it, or an error if unsuccessful.
+//
+// If no path is provided, then a default filename is generated.
+func createFile(path *string, ext string) (*os.File, error) {
+	var outfilePath string
+	// If no path is provided create a default output filename.
+	if path == nil {
+		outfilePath = strings.TrimSuffix(*GetDefaultOutfilePath(), ".csv") + ext
+	} else {
+		// Check that the given output file has the right extension.
+		if filepath.Ext(*path) != ext {
+			return nil, oops.Errorf("output file must be a %s file: %s", strings.ToUpper(ext[1:]), *path)
 		}
+		outfilePath = *path
+	}
+
+	// Create the output file.
+	file, err := os.Create(outfilePath)
+	if err != nil {
+		return nil, oops.Wrapf(err, "unable to create output file for path: %s", outfilePath)
 	}
 
 	return file, nil
 }
 
+// openInfile opens the file at the given path for reading, or returns stdin if
+// the path is StdioPath.
+func openInfile(path string) (io.ReadCloser, error) {
+	if path == StdioPath {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// createOutfile creates a file for the given OutputFormat at the given path, or
+// returns stdout if the path is StdioPath. The returned file should be closed
+// with closeOutfile.
+func createOutfile(path *string, format OutputFormat) (*os.File, error) {
+	if path != nil && *path == StdioPath {
+		return os.Stdout, nil
+	}
+	return createFile(path, format.Extension())
+}
+
+// closeOutfile closes the given file, unless it's stdout.
+func closeOutfile(file *os.File) {
+	if file != os.Stdout {
+		file.Close()
+	}
+}
+
 // TruncateColumnHeaders returns a slice of strings with the longest common
 // prefix among all the elements removed from each.
 func TruncateColumnHeaders(headers []string) []string {
+	return truncateColumnHeaders(headers, oo.DefaultConfig())
+}
+
+// truncateColumnHeaders returns a slice of strings with the longest common
+// prefix among all the elements removed from each. The headers are split into
+// prefix tokens, and joined back together, with the given Config.
+func truncateColumnHeaders(headers []string, cfg *oo.Config) []string {
 	if len(headers) == 0 {
 		return nil
 	}
@@ -100,17 +301,18 @@ func TruncateColumnHeaders(headers []string) []string {
 	// Split each header into a slice of prefix tokens.
 	var split [][]string
 	for _, header := range headers {
-		split = append(split, strings.Split(header, "_"))
+		split = append(split, cfg.SplitHeader(header))
 	}
 
-	// Sort by length so we can easily get the shortest header slice.
-	sort.SliceStable(split, func(i, j int) bool {
-		return len(split[i]) < len(split[j])
+	// Sort a copy by length so we can easily get the shortest header slice.
+	sorted := append([][]string(nil), split...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i]) < len(sorted[j])
 	})
-	shortestHeader := split[0]
+	shortestHeader := sorted[0]
 
-	// Keep a slice of the common prefix tokens.
-	var validPrefs []string
+	// Count the common prefix tokens.
+	var numPrefs int
 
 	// Iterate through each header slice and break out when we encounter a
 	// prefix token that doesn't match corresponding prefix in the shortest
@@ -118,23 +320,27 @@ func TruncateColumnHeaders(headers []string) []string {
 	valid := true
 	for i := 0; i < len(shortestHeader) && valid; i++ {
 		for _, row := range split {
-			if row[i] != split[0][i] {
+			if row[i] != shortestHeader[i] {
 				valid = false
 				break
 			}
 		}
 		if valid {
-			validPrefs = append(validPrefs, split[0][i])
+			numPrefs++
 		}
 	}
 
-	// Join the slice of valid prefixes back into a string and add a trailing
-	// underscore so we can remove the common prefix from each header.
-	longestPrefix := strings.Join(validPrefs, "_") + "_"
+	// If there's no common prefix then there's nothing to remove.
+	if numPrefs == 0 {
+		return headers
+	}
 
-	// Remove the longest common prefix from each header.
-	for i, header := range headers {
-		headers[i] = strings.Replace(header, longestPrefix, "", 1)
+	// Remove the longest common prefix from each header. A header that is
+	// nothing but the common prefix is left as it is.
+	for i, tokens := range split {
+		if len(tokens) > numPrefs {
+			headers[i] = cfg.JoinHeader(tokens[numPrefs:])
+		}
 	}
 
 	return headers
@@ -149,3 +355,9 @@ func GetDefaultOutfilePath() *string {
 	outFilePath := fmt.Sprintf("data_%d.output.csv", timeNowMs)
 	return &outFilePath
 }
+
+// GetDefaultOutdirPath returns the default path for the output directory of
+// normalized tables, of the form `data_<seconds_epoch>`.
+func GetDefaultOutdirPath() string {
+	return fmt.Sprintf("data_%d", time.Now().Unix())
+}
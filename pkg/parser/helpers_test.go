@@ -1,7 +1,10 @@
 package parser_test
 
 import (
+	"bytes"
+	"encoding/json"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -35,6 +38,16 @@ func TestReadJSONFile(t *testing.T) {
 			infilePath:  "../testdata/jsontest.json",
 			expectError: false,
 		},
+		{
+			description: "expect success for infile with array root",
+			infilePath:  "../testdata/jsontest_array.json",
+			expectError: false,
+		},
+		{
+			description: "expect success for infile with scalar root",
+			infilePath:  "../testdata/jsontest_scalar.json",
+			expectError: false,
+		},
 	}
 
 	for _, testcase := range testcases {
@@ -51,6 +64,96 @@ func TestReadJSONFile(t *testing.T) {
 	}
 }
 
+func TestReadJSON(t *testing.T) {
+	testcases := []struct {
+		description string
+		input       string
+		expected    interface{}
+		expectError bool
+	}{
+		{
+			description: "object",
+			input:       `{"key": "val"}`,
+			expected:    map[string]interface{}{"key": "val"},
+		},
+		{
+			description: "array",
+			input:       `[1, "two"]`,
+			expected:    []interface{}{json.Number("1"), "two"},
+		},
+		{
+			description: "expect error for malformed input",
+			input:       `{"key": `,
+			expectError: true,
+		},
+		{
+			description: "expect error for empty input",
+			input:       "",
+			expectError: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			actual, err := parser.ReadJSON(strings.NewReader(testcase.input))
+			assert.Equal(t, testcase.expectError, err != nil)
+			assert.Equal(t, testcase.expected, actual)
+		})
+	}
+}
+
+func TestReadJSONLinesFile(t *testing.T) {
+	testcases := []struct {
+		description string
+		infilePath  string
+		expected    []interface{}
+		expectError bool
+	}{
+		{
+			description: "expect error if infile isn't a json lines file",
+			infilePath:  "../testdata/jsontest.json",
+			expectError: true,
+		},
+		{
+			description: "expect error if infile doesn't exist",
+			infilePath:  "../testdata/nonexistentfile.jsonl",
+			expectError: true,
+		},
+		{
+			description: "expect error if a line is malformed",
+			infilePath:  "../testdata/jsontest_lines_bad.jsonl",
+			expectError: true,
+		},
+		{
+			description: "expect success for valid infile",
+			infilePath:  "../testdata/jsontest_lines.jsonl",
+			expected: []interface{}{
+				map[string]interface{}{
+					"id":    json.Number("1"),
+					"event": map[string]interface{}{"type": "start", "at": json.Number("1591056576414")},
+				},
+				map[string]interface{}{
+					"id":     json.Number("2"),
+					"event":  map[string]interface{}{"type": "stop", "at": json.Number("1591056576500")},
+					"reason": "done",
+				},
+				map[string]interface{}{
+					"id":    json.Number("3"),
+					"extra": true,
+				},
+			},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			rawData, err := parser.ReadJSONLinesFile(testcase.infilePath)
+			assert.Equal(t, testcase.expectError, err != nil)
+			assert.Equal(t, testcase.expected, rawData)
+		})
+	}
+}
+
 func TestWriteCSVFile(t *testing.T) {
 	// This is a valid 2d slice of strings.
 	data := [][]string{
@@ -93,6 +196,16 @@ func TestWriteCSVFile(t *testing.T) {
 	}
 }
 
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := parser.WriteCSV([][]string{
+		{"one", "two"},
+		{"one_one", "two, two"},
+	}, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "one,two\none_one,\"two, two\"\n", buf.String())
+}
+
 func TestTruncateColumnHeaders(t *testing.T) {
 	testcases := []struct {
 		description string
@@ -114,6 +227,11 @@ func TestTruncateColumnHeaders(t *testing.T) {
 			input:       []string{"data_test_one", "data_test_two", "data_three"},
 			expected:    []string{"test_one", "test_two", "three"},
 		},
+		{
+			description: "no common prefix",
+			input:       []string{"id", "name", "tags_color"},
+			expected:    []string{"id", "name", "tags_color"},
+		},
 		{
 			description: "long",
 			input: []string{
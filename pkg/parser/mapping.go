@@ -0,0 +1,178 @@
+package parser
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/samsarahq/go/oops"
+	"gopkg.in/yaml.v3"
+)
+
+// Mapping lists the columns to write, in order, for exports that need a fixed
+// schema. It replaces truncating the column headers.
+type Mapping struct {
+	Columns []MappingColumn `yaml:"columns" json:"columns"`
+}
+
+// MappingColumn is a column of a Mapping. The Source is the untruncated header
+// of the column built from the input, like `data_items_id`, and the Name is the
+// header written in its place, which defaults to the Source.
+//
+// A column without a Default is required, so the conversion fails if the input
+// doesn't have the Source column. A column with a Default gets it in every
+// empty cell, or in every cell if the Source column is missing.
+type MappingColumn struct {
+	Source  string  `yaml:"source" json:"source"`
+	Name    string  `yaml:"name" json:"name"`
+	Default *string `yaml:"default" json:"default"`
+}
+
+// header returns the header written for the MappingColumn.
+func (c MappingColumn) header() string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return c.Source
+}
+
+// ReadMapping reads a Mapping in YAML or JSON from the given io.Reader, and
+// returns an error if it's malformed or has a key a Mapping doesn't have, like
+// a misspelled `nmae`. For example:
+//
+//	columns:
+//	  - source: data_items_id
+//	    name: id
+//	  - source: data_items_status
+//	    name: status
+//	    default: unknown
+func ReadMapping(r io.Reader) (*Mapping, error) {
+	// YAML is a superset of JSON, so this reads either. An empty input has no
+	// columns, which validate reports.
+	dec := yaml.NewDecoder(r)
+	dec.KnownFields(true)
+
+	var m Mapping
+	if err := dec.Decode(&m); err != nil && err != io.EOF {
+		return nil, oops.Wrapf(err, "unable to decode mapping")
+	}
+
+	if err := m.validate(); err != nil {
+		return nil, oops.Wrapf(err, "invalid mapping")
+	}
+	return &m, nil
+}
+
+// ReadMappingFile reads a Mapping from the YAML or JSON file at the given path,
+// see ReadMapping.
+func ReadMappingFile(path string) (*Mapping, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, oops.Wrapf(err, "unable to open mapping file: %s", path)
+	}
+	defer file.Close()
+
+	return ReadMapping(file)
+}
+
+// validate returns an error if the Mapping has no columns, a column without a
+// source, or two columns with the same header.
+func (m *Mapping) validate() error {
+	if len(m.Columns) == 0 {
+		return oops.Errorf("mapping has no columns")
+	}
+
+	seen := make(map[string]bool)
+	for i, c := range m.Columns {
+		if c.Source == "" {
+			return oops.Errorf("column %d has no source", i)
+		}
+		if seen[c.header()] {
+			return oops.Errorf("duplicate column name: %s", c.header())
+		}
+		seen[c.header()] = true
+	}
+	return nil
+}
+
+// apply returns the given parsed data with the columns of the Mapping, in its
+// order and with its headers. Returns an error listing the sources of any
+// required columns that are missing from the data, or if the source of a
+// mapped column matches more than one column of the data, see columns.
+func (m *Mapping) apply(parsed [][]string, sourcesFunc func() ([]string, error)) ([][]string, error) {
+	columns, missing, err := m.columns(parsed[0], sourcesFunc)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return nil, oops.Errorf("input is missing required columns: %s", strings.Join(missing, ", "))
+	}
+	return m.rows(parsed, columns), nil
+}
+
+// columns returns the index in the given header of the data column for each
+// column of the Mapping, or -1 if it's missing, along with the sources of the
+// missing required columns.
+//
+// Different paths can build the same header, so a mapped source can match
+// more than one column. Returns an error naming the paths of those columns,
+// which are returned by the given function, since the Mapping can't tell which
+// one is meant.
+func (m *Mapping) columns(header []string, sourcesFunc func() ([]string, error)) ([]int, []string, error) {
+	index := make(map[string][]int)
+	for i, h := range header {
+		index[h] = append(index[h], i)
+	}
+
+	columns := make([]int, len(m.Columns))
+	var missing []string
+	var conflicts [][]int
+	for i, c := range m.Columns {
+		matches := index[c.Source]
+		switch len(matches) {
+		case 0:
+			columns[i] = -1
+			if c.Default == nil {
+				missing = append(missing, c.Source)
+			}
+		case 1:
+			columns[i] = matches[0]
+		default:
+			conflicts = append(conflicts, matches)
+		}
+	}
+	if len(conflicts) == 0 {
+		return columns, missing, nil
+	}
+
+	sources, err := columnSources(header, sourcesFunc)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, nil, oops.Errorf("mapped sources match more than one column: %s", describeConflicts(header, sources, conflicts))
+}
+
+// rows returns the given parsed data with the columns of the Mapping, taking
+// each one from the data column at the given index, or leaving it empty if the
+// index is -1. Empty cells get the column's default.
+func (m *Mapping) rows(parsed [][]string, columns []int) [][]string {
+	header := make([]string, len(m.Columns))
+	for i, c := range m.Columns {
+		header[i] = c.header()
+	}
+
+	ret := [][]string{header}
+	for _, row := range parsed[1:] {
+		mapped := make([]string, len(m.Columns))
+		for i, c := range m.Columns {
+			if columns[i] >= 0 {
+				mapped[i] = row[columns[i]]
+			}
+			if mapped[i] == "" && c.Default != nil {
+				mapped[i] = *c.Default
+			}
+		}
+		ret = append(ret, mapped)
+	}
+	return ret
+}
@@ -0,0 +1,185 @@
+package parser_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ecshreve/jcgo/pkg/parser"
+)
+
+func TestReadMapping(t *testing.T) {
+	unknown := "unknown"
+
+	testcases := []struct {
+		description string
+		input       string
+		expected    *parser.Mapping
+		expectError bool
+	}{
+		{
+			description: "yaml",
+			input:       "columns:\n  - source: data_id\n    name: id\n  - source: data_status\n    default: unknown\n",
+			expected: &parser.Mapping{Columns: []parser.MappingColumn{
+				{Source: "data_id", Name: "id"},
+				{Source: "data_status", Default: &unknown},
+			}},
+		},
+		{
+			description: "json",
+			input:       `{"columns": [{"source": "data_id", "name": "id"}, {"source": "data_status", "default": "unknown"}]}`,
+			expected: &parser.Mapping{Columns: []parser.MappingColumn{
+				{Source: "data_id", Name: "id"},
+				{Source: "data_status", Default: &unknown},
+			}},
+		},
+		{
+			description: "expect error for no columns",
+			input:       "columns: []\n",
+			expectError: true,
+		},
+		{
+			description: "expect error for a column without a source",
+			input:       "columns:\n  - name: id\n",
+			expectError: true,
+		},
+		{
+			description: "expect error for duplicate names",
+			input:       "columns:\n  - source: a_id\n    name: id\n  - source: id\n",
+			expectError: true,
+		},
+		{
+			description: "expect error for a misspelled column key",
+			input:       "columns:\n  - source: data_id\n    nmae: id\n",
+			expectError: true,
+		},
+		{
+			description: "expect error for a misspelled top-level key",
+			input:       `{"colums": [{"source": "data_id"}]}`,
+			expectError: true,
+		},
+		{
+			description: "expect error for empty input",
+			input:       "",
+			expectError: true,
+		},
+		{
+			description: "expect error for malformed input",
+			input:       "columns: [",
+			expectError: true,
+		},
+		{
+			description: "expect error for input the decoder can't parse",
+			input:       "0: [:!00 \xef",
+			expectError: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			actual, err := parser.ReadMapping(strings.NewReader(testcase.input))
+			if testcase.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expected, actual)
+		})
+	}
+}
+
+func TestConvertWithMapping(t *testing.T) {
+	mapping, err := parser.ReadMapping(strings.NewReader(`
+columns:
+  - source: data_items_status
+    name: status
+    default: unknown
+  - source: data_items_id
+    name: id
+  - source: data_region
+    name: region
+    default: us
+`))
+	assert.NoError(t, err)
+
+	testcases := []struct {
+		description string
+		input       string
+		opts        []parser.Option
+		expected    string
+		expectError string
+	}{
+		{
+			description: "mapped columns in order",
+			input:       `{"data": {"items": [{"id": 1, "status": "ok", "extra": true}, {"id": 2}]}}`,
+			expected:    "status,id,region\nok,1,us\nunknown,2,us\n",
+		},
+		{
+			description: "mapped columns when streaming",
+			input:       `[{"data": {"items": [{"id": 1, "status": "ok"}]}}, {"data": {"items": [{"id": 2}], "region": "eu"}}]`,
+			opts:        []parser.Option{parser.WithStreaming(true)},
+			expected:    "status,id,region\nok,1,us\nunknown,2,eu\n",
+		},
+		{
+			description: "expect error for missing required column",
+			input:       `{"data": {"items": [{"status": "ok"}]}}`,
+			expectError: `missing required columns: data_items_id`,
+		},
+		{
+			description: "expect error for a source matching more than one column",
+			input:       `{"data": {"items": [{"id": 1}], "items_id": 2}}`,
+			expectError: `"data_items_id" from $.data.items[*].id, $.data.items_id`,
+		},
+		{
+			description: "expect error for a source matching more than one column when streaming",
+			input:       `[{"data": {"items": [{"id": 1}], "items_id": 2}}]`,
+			opts:        []parser.Option{parser.WithStreaming(true)},
+			expectError: `"data_items_id" from $.data.items[*].id, $.data.items_id`,
+		},
+		{
+			description: "unmapped columns can share a header",
+			input:       `{"data": {"items": [{"id": 1}], "x": {"y": 2}, "x_y": 3}}`,
+			expected:    "status,id,region\nunknown,1,us\n",
+		},
+		{
+			description: "required column missing from a later record",
+			input:       `[{"data": {"items": [{"id": 1}]}}, {"data": {"other": 2}}]`,
+			expected:    "status,id,region\nunknown,1,us\nunknown,,us\n",
+		},
+		{
+			description: "required column missing from a later record when streaming",
+			input:       `[{"data": {"items": [{"id": 1}]}}, {"data": {"other": 2}}]`,
+			opts:        []parser.Option{parser.WithStreaming(true)},
+			expected:    "status,id,region\nunknown,1,us\nunknown,,us\n",
+		},
+		{
+			description: "expect error for missing required column in the first record when streaming",
+			input:       `[{"data": {"other": 2}}, {"data": {"items": [{"id": 1}]}}]`,
+			opts:        []parser.Option{parser.WithStreaming(true)},
+			expectError: `missing required columns: data_items_id`,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			opts := append([]parser.Option{parser.WithMapping(mapping)}, testcase.opts...)
+
+			var buf bytes.Buffer
+			err := parser.Convert(context.Background(), strings.NewReader(testcase.input), &buf, opts...)
+			if testcase.expectError != "" {
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), testcase.expectError)
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expected, buf.String())
+		})
+	}
+
+	_, err = parser.ConvertTables(context.Background(), strings.NewReader(`{"id": 1}`), parser.WithMapping(mapping))
+	assert.Error(t, err)
+}
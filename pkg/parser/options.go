@@ -0,0 +1,499 @@
+package parser
+
+import (
+	"github.com/samsarahq/go/oops"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
+)
+
+// Option configures how a Parser converts JSON to CSV.
+type Option func(*options)
+
+// options holds the settings for a Parser, set by applying Options to the
+// defaults from defaultOptions.
+type options struct {
+	truncateHeaders  bool
+	truncateStrategy TruncateStrategy
+	duplicateHeaders DuplicateHeaderPolicy
+	inputFormat      InputFormat
+	outputFormat     OutputFormat
+	delimiter        rune
+	stream           bool
+	streamPath       string
+	root             string
+	mapping          *Mapping
+	object           oo.Config
+}
+
+// defaultOptions returns the options used by a Parser if none are given.
+func defaultOptions() options {
+	return options{
+		truncateHeaders: true,
+		inputFormat:     FormatAuto,
+		outputFormat:    FormatCSV,
+		object:          *oo.DefaultConfig(),
+	}
+}
+
+// validate returns an error if the options can't be used together.
+func (o options) validate() error {
+	if !validDelimiter(o.comma()) {
+		return oops.Errorf("invalid delimiter: %q", o.comma())
+	}
+	if o.root != "" {
+		if o.stream {
+			return oops.Errorf("a root can't be selected when streaming, use a stream path instead")
+		}
+		if _, err := parseRoot(o.root); err != nil {
+			return oops.Wrapf(err, "invalid root")
+		}
+	}
+	if err := o.object.Validate(); err != nil {
+		return oops.Wrapf(err, "invalid object config")
+	}
+	return nil
+}
+
+// ValidateOptions returns an error if the given Options can't be used together.
+// Convert returns the same error, this lets it be checked up front.
+func ValidateOptions(opts ...Option) error {
+	return newOptions(opts...).validate()
+}
+
+// comma returns the field delimiter to write the output with.
+func (o options) comma() rune {
+	if o.delimiter != 0 {
+		return o.delimiter
+	}
+	return o.outputFormat.Delimiter()
+}
+
+// newOptions returns the default options with the given Options applied in
+// order, so later Options override earlier ones.
+func newOptions(opts ...Option) options {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithTruncateHeaders sets whether the longest common prefix among the column
+// headers is removed before writing the CSV. Defaults to true.
+func WithTruncateHeaders(truncate bool) Option {
+	return func(o *options) {
+		o.truncateHeaders = truncate
+	}
+}
+
+// WithTruncateStrategy sets how the column headers are shortened when they're
+// truncated. Defaults to TruncateCommonPrefix, which removes the longest common
+// prefix. TruncateUniqueSuffix reduces each header to the shortest suffix of its
+// keys that no other header ends with.
+func WithTruncateStrategy(strategy TruncateStrategy) Option {
+	return func(o *options) {
+		o.truncateStrategy = strategy
+	}
+}
+
+// WithDuplicateHeaders sets what happens when two columns of the output have
+// the same header. Defaults to DuplicateError, which fails the conversion and
+// names the paths the columns come from. The headers are checked after they're
+// truncated, and aren't checked at all with a Mapping, whose names are unique.
+func WithDuplicateHeaders(policy DuplicateHeaderPolicy) Option {
+	return func(o *options) {
+		o.duplicateHeaders = policy
+	}
+}
+
+// WithInputFormat sets the format of the input. Defaults to FormatAuto, which
+// is FormatJSON unless the input is a file with an NDJSON extension.
+func WithInputFormat(format InputFormat) Option {
+	return func(o *options) {
+		o.inputFormat = format
+	}
+}
+
+// WithStreaming sets whether the input is converted one record at a time,
+// without loading the whole input into memory. Defaults to false.
+//
+// When streaming, the header row is taken from the first record, and every
+// later record must only use columns the first record has.
+func WithStreaming(stream bool) Option {
+	return func(o *options) {
+		o.stream = stream
+	}
+}
+
+// WithStreamPath sets the JSON Pointer to the array of records to convert when
+// streaming. Defaults to the root of the document.
+func WithStreamPath(pointer string) Option {
+	return func(o *options) {
+		o.streamPath = pointer
+	}
+}
+
+// WithRoot sets the part of the input to convert, as a JSON Pointer like
+// `/data/items`, or a JSONPath like `$.data.items` or `$.pages[*].items`.
+// Defaults to the whole document.
+//
+// A JSONPath with a wildcard can match many values, which are converted as
+// records like the elements of a root array. For NDJSON input the values
+// matched in every line are records. The root can't be used when streaming,
+// see WithStreamPath instead.
+func WithRoot(expr string) Option {
+	return func(o *options) {
+		o.root = expr
+	}
+}
+
+// WithMapping sets the columns to write, in order, with their headers and
+// default values, see Mapping. Headers aren't truncated when there's a
+// Mapping. Defaults to nil, which writes every column.
+func WithMapping(m *Mapping) Option {
+	return func(o *options) {
+		o.mapping = m
+	}
+}
+
+// WithOutputFormat sets the format of the output. Defaults to FormatCSV.
+func WithOutputFormat(format OutputFormat) Option {
+	return func(o *options) {
+		o.outputFormat = format
+	}
+}
+
+// WithDelimiter sets the field delimiter of the output, overriding the one
+// used by the output format. See ParseDelimiter for the valid delimiters.
+func WithDelimiter(delimiter rune) Option {
+	return func(o *options) {
+		o.delimiter = delimiter
+	}
+}
+
+// WithNullToken sets the value written for JSON null values. Defaults to an
+// empty string.
+func WithNullToken(token string) Option {
+	return func(o *options) {
+		o.object.NullToken = token
+	}
+}
+
+// HeaderStyle describes how the keys on the path to a value are joined to build
+// the value's column header.
+type HeaderStyle = oo.HeaderStyle
+
+// The HeaderStyles that can be given to WithHeaderStyle.
+const (
+	HeaderPlain   = oo.HeaderPlain
+	HeaderEscaped = oo.HeaderEscaped
+	HeaderPointer = oo.HeaderPointer
+)
+
+// ParseHeaderStyle returns the HeaderStyle with the given name, or an error if
+// the name doesn't match any HeaderStyle.
+func ParseHeaderStyle(name string) (HeaderStyle, error) {
+	return oo.ParseHeaderStyle(name)
+}
+
+// WithHeaderStyle sets how keys are joined and escaped to build the column
+// headers. Defaults to HeaderPlain, which joins keys with the header separator
+// as they are, so a key containing the separator makes the header ambiguous.
+// HeaderEscaped escapes the separator inside keys with a backslash, and
+// HeaderPointer builds JSON Pointer headers like `/owner/user_id`.
+func WithHeaderStyle(style HeaderStyle) Option {
+	return func(o *options) {
+		o.object.HeaderStyle = style
+	}
+}
+
+// WithHeaderSeparator sets the string placed between the keys of nested values
+// to build the column headers. Defaults to "_". It isn't used with
+// HeaderPointer.
+func WithHeaderSeparator(separator string) Option {
+	return func(o *options) {
+		o.object.Separator = separator
+	}
+}
+
+// KeyOrder describes the order of the columns built from the keys of a map.
+type KeyOrder = oo.KeyOrder
+
+// The KeyOrders that can be given to WithKeyOrder.
+const (
+	KeyOrderAlphabetical = oo.KeyOrderAlphabetical
+	KeyOrderDocument     = oo.KeyOrderDocument
+)
+
+// ParseKeyOrder returns the KeyOrder with the given name, or an error if the
+// name doesn't match any KeyOrder.
+func ParseKeyOrder(name string) (KeyOrder, error) {
+	return oo.ParseKeyOrder(name)
+}
+
+// WithKeyOrder sets the order of the columns built from the keys of each map.
+// Defaults to KeyOrderAlphabetical. With KeyOrderDocument the columns follow
+// the order the keys first appear in the input.
+func WithKeyOrder(order KeyOrder) Option {
+	return func(o *options) {
+		o.object.KeyOrder = order
+	}
+}
+
+// EmptyPolicy describes how empty JSON arrays and maps are written.
+type EmptyPolicy = oo.EmptyPolicy
+
+// The EmptyPolicies that can be given to WithEmptyPolicy.
+const (
+	EmptyBlank   = oo.EmptyBlank
+	EmptyLiteral = oo.EmptyLiteral
+	EmptyDrop    = oo.EmptyDrop
+)
+
+// ParseEmptyPolicy returns the EmptyPolicy with the given name, or an error if
+// the name doesn't match any EmptyPolicy.
+func ParseEmptyPolicy(name string) (EmptyPolicy, error) {
+	return oo.ParseEmptyPolicy(name)
+}
+
+// WithEmptyPolicy sets how empty arrays and maps are written. Defaults to
+// EmptyBlank, which keeps the row with a blank cell for the empty value.
+// EmptyLiteral writes `[]` or `{}` in the cell instead, and EmptyDrop drops the
+// row holding the empty value.
+func WithEmptyPolicy(policy EmptyPolicy) Option {
+	return func(o *options) {
+		o.object.EmptyPolicy = policy
+	}
+}
+
+// NumberFormat describes how numbers are written.
+type NumberFormat = oo.NumberFormat
+
+// The NumberFormats that can be given to WithNumberFormat.
+const (
+	NumberOriginal   = oo.NumberOriginal
+	NumberFixed      = oo.NumberFixed
+	NumberScientific = oo.NumberScientific
+)
+
+// MaxFixedDigits is the most digits a number is written with in fixed point
+// notation, and the highest precision that can be given to WithNumberPrecision.
+const MaxFixedDigits = oo.MaxFixedDigits
+
+// ParseNumberFormat returns the NumberFormat with the given name, or an error
+// if the name doesn't match any NumberFormat.
+func ParseNumberFormat(name string) (NumberFormat, error) {
+	return oo.ParseNumberFormat(name)
+}
+
+// WithNumberFormat sets how numbers are written. Defaults to NumberOriginal,
+// which writes each number exactly as it appears in the input. NumberFixed and
+// NumberScientific write every number in fixed point or scientific notation,
+// with the digits set by WithNumberPrecision. A number that needs more than
+// MaxFixedDigits digits in fixed point notation is written in scientific
+// notation instead.
+func WithNumberFormat(format NumberFormat) Option {
+	return func(o *options) {
+		o.object.NumberFormat = format
+	}
+}
+
+// WithNumberPrecision sets the number of digits after the decimal point for the
+// NumberFixed and NumberScientific formats, at most MaxFixedDigits. Defaults to
+// -1, which uses the fewest digits needed to write each number exactly.
+func WithNumberPrecision(precision int) Option {
+	return func(o *options) {
+		o.object.NumberPrecision = precision
+	}
+}
+
+// ArrayMode describes how the elements of nested arrays are written.
+type ArrayMode = oo.ArrayMode
+
+// The ArrayModes that can be given to WithArrayMode.
+const (
+	ArrayRows    = oo.ArrayRows
+	ArrayColumns = oo.ArrayColumns
+)
+
+// ParseArrayMode returns the ArrayMode with the given name, or an error if the
+// name doesn't match any ArrayMode.
+func ParseArrayMode(name string) (ArrayMode, error) {
+	return oo.ParseArrayMode(name)
+}
+
+// WithArrayMode sets how the elements of nested arrays are written. Defaults to
+// ArrayRows, which writes each element in its own rows. ArrayColumns writes
+// each element in its own columns instead, like `events_0_eventAt`, so every
+// record takes a single row. The elements of a root array, or the lines of
+// NDJSON input, are always records in their own rows.
+func WithArrayMode(mode ArrayMode) Option {
+	return func(o *options) {
+		o.object.ArrayMode = mode
+	}
+}
+
+// WithMaxArrayElements sets the most elements of each array written in their
+// own columns with ArrayColumns, later elements are left out. Defaults to 0,
+// which means no limit.
+func WithMaxArrayElements(max int) Option {
+	return func(o *options) {
+		o.object.MaxArrayElements = max
+	}
+}
+
+// SiblingMode describes how the rows of sibling values in an object, like two
+// arrays under the same parent, are combined.
+type SiblingMode = oo.SiblingMode
+
+// The SiblingModes that can be given to WithSiblingMode.
+const (
+	SiblingRepeat = oo.SiblingRepeat
+	SiblingZip    = oo.SiblingZip
+)
+
+// ParseSiblingMode returns the SiblingMode with the given name, or an error if
+// the name doesn't match any SiblingMode.
+func ParseSiblingMode(name string) (SiblingMode, error) {
+	return oo.ParseSiblingMode(name)
+}
+
+// WithSiblingMode sets how the rows of sibling arrays are combined. Defaults to
+// SiblingRepeat, which repeats the last row of a shorter array. SiblingZip
+// pairs up the arrays element by element instead, like `timestamps` and
+// `values`, following the ZipPolicy given with WithZipPolicy.
+func WithSiblingMode(mode SiblingMode) Option {
+	return func(o *options) {
+		o.object.SiblingMode = mode
+	}
+}
+
+// ZipPolicy describes how sibling arrays of different lengths are zipped
+// together with SiblingZip.
+type ZipPolicy = oo.ZipPolicy
+
+// The ZipPolicies that can be given to WithZipPolicy.
+const (
+	ZipPad      = oo.ZipPad
+	ZipTruncate = oo.ZipTruncate
+	ZipError    = oo.ZipError
+)
+
+// ParseZipPolicy returns the ZipPolicy with the given name, or an error if the
+// name doesn't match any ZipPolicy.
+func ParseZipPolicy(name string) (ZipPolicy, error) {
+	return oo.ParseZipPolicy(name)
+}
+
+// WithZipPolicy sets how sibling arrays of different lengths are zipped
+// together with SiblingZip. Defaults to ZipPad, which pads the shorter arrays
+// with blank cells. ZipTruncate drops the rows past the end of the shortest
+// array, and ZipError fails the conversion.
+func WithZipPolicy(policy ZipPolicy) Option {
+	return func(o *options) {
+		o.object.ZipPolicy = policy
+	}
+}
+
+// ScalarArrayMode describes how arrays whose elements are all scalar values are
+// written.
+type ScalarArrayMode = oo.ScalarArrayMode
+
+// The ScalarArrayModes that can be given to WithScalarArrayMode.
+const (
+	ScalarArrayExpand = oo.ScalarArrayExpand
+	ScalarArrayJoin   = oo.ScalarArrayJoin
+	ScalarArrayJSON   = oo.ScalarArrayJSON
+)
+
+// ParseScalarArrayMode returns the ScalarArrayMode with the given name, or an
+// error if the name doesn't match any ScalarArrayMode.
+func ParseScalarArrayMode(name string) (ScalarArrayMode, error) {
+	return oo.ParseScalarArrayMode(name)
+}
+
+// WithScalarArrayMode sets how nested arrays whose elements are all scalar
+// values are written. Defaults to ScalarArrayExpand, which follows the
+// ArrayMode like any other array. ScalarArrayJoin writes the elements in a
+// single cell joined by the scalar array separator, and ScalarArrayJSON writes
+// them in a single cell as a JSON array.
+func WithScalarArrayMode(mode ScalarArrayMode) Option {
+	return func(o *options) {
+		o.object.ScalarArrayMode = mode
+	}
+}
+
+// WithScalarArraySeparator sets the string placed between the elements of a
+// scalar array joined with ScalarArrayJoin. Defaults to "|".
+func WithScalarArraySeparator(separator string) Option {
+	return func(o *options) {
+		o.object.ScalarArraySeparator = separator
+	}
+}
+
+// WithMaxDepth sets the most keys in a column header. A map or array nested
+// that deep is written as compact JSON in a single cell under its header,
+// instead of being flattened further. Defaults to 0, which means no limit.
+func WithMaxDepth(depth int) Option {
+	return func(o *options) {
+		o.object.MaxDepth = depth
+	}
+}
+
+// WithMaxRows sets the most data rows the output can have. The conversion fails
+// before building the rows if the input would give more. Defaults to 0, which
+// means no limit.
+func WithMaxRows(max int) Option {
+	return func(o *options) {
+		o.object.MaxRows = max
+	}
+}
+
+// WithMaxColumns sets the most columns the output can have. Defaults to 0, which
+// means no limit.
+func WithMaxColumns(max int) Option {
+	return func(o *options) {
+		o.object.MaxColumns = max
+	}
+}
+
+// WithMaxCells sets the most cells the output can have, counting the cells of
+// the data rows. Defaults to 0, which means no limit.
+func WithMaxCells(max int) Option {
+	return func(o *options) {
+		o.object.MaxCells = max
+	}
+}
+
+// PathPattern matches the column headers of the values to include or exclude,
+// see ParsePathPattern.
+type PathPattern = oo.PathPattern
+
+// ParsePathPattern returns the PathPattern for the given pattern, or an error if
+// it's malformed. A pattern starting with `re:` is a regular expression that
+// can match any part of a header. Any other pattern is a glob that must match
+// the whole header, where `*` matches any run of characters and `?` any one
+// character.
+func ParsePathPattern(pattern string) (*PathPattern, error) {
+	return oo.ParsePathPattern(pattern)
+}
+
+// WithInclude adds patterns selecting the columns to write, matched against the
+// untruncated column headers. Only the values whose header matches one of the
+// patterns, and everything nested in them, are written. Defaults to writing
+// every value.
+func WithInclude(patterns ...*PathPattern) Option {
+	return func(o *options) {
+		o.object.Include = append(o.object.Include, patterns...)
+	}
+}
+
+// WithExclude adds patterns selecting the columns to leave out, matched against
+// the untruncated column headers. A value whose header matches one of the
+// patterns is left out with everything nested in it, before it's converted, so
+// excluded values cost nothing.
+func WithExclude(patterns ...*PathPattern) Option {
+	return func(o *options) {
+		o.object.Exclude = append(o.object.Exclude, patterns...)
+	}
+}
@@ -1,69 +1,158 @@
-// Package parser provides implementation of a Parser that converts a JSON file
-// to a CSV file. It also provides helper functions to read JSON files and write
-// CSV files.
+// Package parser provides implementation of a Parser that converts JSON to CSV.
+// It also provides helper functions to read JSON files and write CSV files.
 package parser
 
 import (
+	"context"
+	"io"
 	"os"
+	"path/filepath"
 
 	"github.com/samsarahq/go/oops"
 
 	oo "github.com/ecshreve/jcgo/internal/object"
 )
 
+// RootValueHeader is the column header used for scalar values that sit at the
+// root of the JSON document and so have no key of their own.
+const RootValueHeader = "value"
+
 // Parser is a representation of a JSON to CSV parsing session.
+//
+// By default the whole input is read into memory and parsed before anything is
+// written. When streaming, the records of the input are read, parsed and
+// written one at a time instead, and the Raw, RootObj and ParsedData fields
+// aren't used.
+//
+// When converting to normalized tables, the Tables field is set instead of the
+// ParsedData field.
 type Parser struct {
-	Raw             *map[string]interface{}
-	RootObj         oo.Object
-	ParsedData      [][]string
-	TruncateHeaders bool
-	InfilePath      *string
-	OutfilePath     *string
-	Outfile         *os.File
+	Raw        interface{}
+	RootObj    oo.Object
+	ParsedData [][]string
+	Tables     []Table
+	opts       options
 }
 
-// NewParser returns a new instance of a Parser.
-func NewParser(truncateHeaders bool, infilePath, outfilePath *string) *Parser {
+// NewParser returns a new instance of a Parser configured with the given
+// Options.
+func NewParser(opts ...Option) *Parser {
 	return &Parser{
-		TruncateHeaders: truncateHeaders,
-		InfilePath:      infilePath,
-		OutfilePath:     outfilePath,
+		opts: newOptions(opts...),
 	}
 }
 
+// Convert reads JSON from the given io.Reader and writes it as CSV to the given
+// io.Writer, configured with the given Options. Returns an error if the
+// conversion was unsuccessful, or if the context is cancelled before it's done.
+func Convert(ctx context.Context, r io.Reader, w io.Writer, opts ...Option) error {
+	return NewParser(opts...).Convert(ctx, r, w)
+}
+
 // ConvertJSONFile converts a JSON file at the given path to a CSV file, and
-// returns a pointer to the newly created file, or an error if unsuccessful.
-func ConvertJSONFile(infilePath, outfilePath *string) (*os.File, error) {
-	pp := NewParser(true, infilePath, outfilePath)
+// returns a pointer to the newly created file, or an error if unsuccessful. The
+// returned file has already been closed.
+//
+// Either path can be StdioPath to use stdin or stdout. If no output path is
+// given then a default one is generated. Unless the input format is given in
+// the Options, it's detected from the extension of the input file, see
+// DetectInputFormat.
+func ConvertJSONFile(infilePath, outfilePath *string, opts ...Option) (*os.File, error) {
+	pp, err := newFileParser(*infilePath, opts...)
+	if err != nil {
+		return nil, err
+	}
 
-	err := pp.readJSONFile()
+	infile, err := openInfile(*infilePath)
 	if err != nil {
-		return nil, oops.Wrapf(err, "unable to read json file: %s", *infilePath)
+		return nil, oops.Wrapf(err, "unable to open file %s", *infilePath)
 	}
+	defer infile.Close()
 
-	err = pp.buildRootObj()
+	outfile, err := createOutfile(outfilePath, pp.opts.outputFormat)
 	if err != nil {
-		return nil, oops.Wrapf(err, "unable to build root object for map: %v", pp.Raw)
+		return nil, oops.Wrapf(err, "unable to create %s file", pp.opts.outputFormat)
+	}
+	defer closeOutfile(outfile)
+
+	// Don't leave a partially written file behind if the conversion fails.
+	if err := pp.Convert(context.Background(), infile, outfile); err != nil {
+		if outfile != os.Stdout {
+			os.Remove(outfile.Name())
+		}
+		return nil, oops.Wrapf(err, "unable to convert file: %s", *infilePath)
+	}
+
+	return outfile, nil
+}
+
+// newFileParser returns a Parser configured with the given Options to convert
+// the file at the given path. Unless the input format is set explicitly, it's
+// detected from the extension, and only files with the extension of a known
+// format are accepted.
+func newFileParser(infilePath string, opts ...Option) (*Parser, error) {
+	format := newOptions(opts...).inputFormat
+	if format == FormatAuto {
+		format = DetectInputFormat(infilePath)
+		ext := filepath.Ext(infilePath)
+		if infilePath != StdioPath && ext != ".json" && format != FormatNDJSON {
+			return nil, oops.Errorf("input file must be a JSON file: %s", infilePath)
+		}
+	}
+	return NewParser(append(opts[:len(opts):len(opts)], WithInputFormat(format))...), nil
+}
+
+// Convert reads JSON from the given io.Reader and writes it as CSV to the given
+// io.Writer. Returns an error if the conversion was unsuccessful, or if the
+// context is cancelled before it's done.
+func (p *Parser) Convert(ctx context.Context, r io.Reader, w io.Writer) error {
+	if err := p.opts.validate(); err != nil {
+		return oops.Wrapf(err, "invalid options")
 	}
 
-	err = pp.parse()
+	if err := ctx.Err(); err != nil {
+		return oops.Wrapf(err, "converting cancelled")
+	}
+
+	if p.opts.stream {
+		return p.stream(ctx, r, w)
+	}
+
+	err := p.read(r)
+	if err != nil {
+		return oops.Wrapf(err, "unable to read input")
+	}
+
+	err = p.buildRootObj()
+	if err != nil {
+		return oops.Wrapf(err, "unable to build root object for value: %v", p.Raw)
+	}
+
+	err = p.parse(ctx)
 	if err != nil {
-		return nil, oops.Wrapf(err, "unable to parse root object: %v", pp.RootObj)
+		return oops.Wrapf(err, "unable to parse root object: %v", p.RootObj)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return oops.Wrapf(err, "writing cancelled")
 	}
 
-	err = pp.writeCSVFile()
+	err = p.write(w)
 	if err != nil {
-		return nil, oops.Wrapf(err, "unable to write data to csv file, data: %v", pp.ParsedData)
+		return oops.Wrapf(err, "unable to write data as csv, data: %v", p.ParsedData)
 	}
 
-	return pp.Outfile, nil
+	return nil
 }
 
 // buildRootObj sets the Parser's RootObj field to the Object representation of
-// the map defined in the Parser's Raw field. It returns an error if unable to
+// the value defined in the Parser's Raw field. It returns an error if unable to
 // build the Object.
+//
+// A root array becomes an ArrayObj, so each of its elements is parsed into its
+// own row(s) of the output.
 func (p *Parser) buildRootObj() error {
-	obj, err := oo.FromInterface("", *p.Raw)
+	obj, err := p.opts.object.FromInterface("", p.Raw)
 	if err != nil {
 		return oops.Wrapf(err, "unable to build Object from interface")
 	}
@@ -74,62 +163,172 @@ func (p *Parser) buildRootObj() error {
 
 // parse sets the Parser's ParsedData field to a 2d slice of strings built from
 // the Parser's RootObj, returns an error if unsuccessful.
-func (p *Parser) parse() error {
+//
+// An input without any records or columns, like an empty array, leaves the
+// ParsedData empty, the same as when it's streamed.
+func (p *Parser) parse(ctx context.Context) error {
 	if p.RootObj == nil {
 		return oops.Errorf("no root object defined on Parser")
 	}
 
+	if records, ok := p.Raw.([]interface{}); ok && len(records) == 0 {
+		p.ParsedData = nil
+		return nil
+	}
+
+	// Make sure the Object fits in the output limits before building any rows.
+	if err := p.opts.object.CheckLimits(p.RootObj); err != nil {
+		return oops.Wrapf(err, "output too large")
+	}
+
 	// Parse the Object into a [][]string.
-	parsed, err := p.RootObj.Parse()
+	var parsed [][]string
+	var err error
+	if p.opts.inputFormat == FormatNDJSON {
+		parsed, err = parseRecords(ctx, p.RootObj)
+	} else {
+		parsed, err = p.RootObj.Parse()
+	}
 	if err != nil {
 		return oops.Wrapf(err, "unable to parse Object")
 	}
+	if len(parsed) == 0 || len(parsed[0]) == 0 {
+		p.ParsedData = nil
+		return nil
+	}
+
+	fillRootHeaders(parsed[0])
 
 	p.ParsedData = parsed
 	return nil
 }
 
-// readJSONFile reads the JSON file specified by the Parser's InfilePath field
-// and stores the resulting map in the Parser's Raw field. Returns an error if
-// reading the file was unnsuccessful.
-func (p *Parser) readJSONFile() error {
-	raw, err := ReadJSONFile(*p.InfilePath)
+// fillRootHeaders sets a default header for any empty header in the given
+// slice. A scalar root value, or an array of scalar values at the root, has no
+// key to use as a column header.
+func fillRootHeaders(headers []string) {
+	for i, header := range headers {
+		if header == "" {
+			headers[i] = RootValueHeader
+		}
+	}
+}
+
+// stream reads the records from the given io.Reader, and writes the rows parsed
+// from each record to the given io.Writer as it goes. Returns an error if
+// unsuccessful.
+func (p *Parser) stream(ctx context.Context, r io.Reader, w io.Writer) error {
+	records, err := newRecordStream(r, p.opts)
 	if err != nil {
-		return oops.Wrapf(err, "unable to read json file: %s", *p.InfilePath)
+		return oops.Wrapf(err, "unable to read records")
 	}
 
-	// Store a pointer to the map in the Parser.
-	p.Raw = raw
+	if err := writeRecords(ctx, records, w, p.opts); err != nil {
+		return oops.Wrapf(err, "unable to write records")
+	}
 
 	return nil
 }
 
-// writeCSVFile writes the data in the Parser's ParsedData field to the CSV file
-// defined in the Parser's OutfilePath field. Returns an error if unsuccessful.
+// parseRecords parses each element of the given ArrayObj as a separate record,
+// and merges the results so every row shares the same header. Returns an error
+// if unable to parse any of the records.
 //
-// If the Parser's TruncateHeaders field is set to true then headers in the
-// first row of the Parser's ParsedData field are truncated prior to writing the
-// CSV file.
-func (p *Parser) writeCSVFile() error {
-	// Check if an OutfilePath is already defined, if not set it to the default.
-	if p.OutfilePath == nil {
-		p.OutfilePath = GetDefaultOutfilePath()
+// This is used for NDJSON input, where each line is a record on its own and
+// the records don't need to share the same set of keys.
+func parseRecords(ctx context.Context, obj oo.Object) ([][]string, error) {
+	arr, ok := obj.(*oo.ArrayObj)
+	if !ok {
+		return nil, oops.Errorf("expected records in an ArrayObj, got: %T", obj)
 	}
 
-	// If the Parser is configured to do so, remove the longest common prefix
-	// among all of the header strings.
-	if p.TruncateHeaders {
-		p.ParsedData[0] = TruncateColumnHeaders(p.ParsedData[0])
+	var records [][][]string
+	for _, item := range arr.Val {
+		if err := ctx.Err(); err != nil {
+			return nil, oops.Wrapf(err, "parsing records cancelled")
+		}
+
+		parsed, err := item.Parse()
+		if err != nil {
+			return nil, oops.Wrapf(err, "unable to parse record: %+v", item)
+		}
+		records = append(records, parsed)
 	}
 
-	// Write the Parser's ParsedData to a csv file.
-	outfile, err := WriteCSVFile(p.ParsedData, p.OutfilePath)
+	return oo.MergeParsed(records...), nil
+}
+
+// read reads JSON from the given io.Reader and stores the resulting value in
+// the Parser's Raw field. Returns an error if reading was unnsuccessful.
+//
+// For NDJSON input the Raw field holds a slice with the value of each line. If
+// the Parser keeps keys in document order, maps are read as OrderedMaps. If a
+// root is set, only the part of the input it selects is kept.
+func (p *Parser) read(r io.Reader) error {
+	ordered := p.opts.object.KeyOrder == oo.KeyOrderDocument
+
+	var raw interface{}
+	var err error
+	switch p.opts.inputFormat {
+	case FormatNDJSON:
+		raw, err = readJSONLines(r, ordered)
+	default:
+		raw, err = readJSON(r, ordered)
+	}
 	if err != nil {
-		return oops.Wrapf(err, "unable to write csv file: %s", *p.OutfilePath)
+		return oops.Wrapf(err, "unable to read %s input", p.opts.inputFormat)
 	}
 
-	// Store a reference to the output file in the Parser.
-	p.Outfile = outfile
+	raw, err = p.applyRoot(raw)
+	if err != nil {
+		return oops.Wrapf(err, "unable to select root")
+	}
+
+	// Store the decoded value in the Parser.
+	p.Raw = raw
+
+	return nil
+}
+
+// write writes the data in the Parser's ParsedData field to the given io.Writer
+// in the Parser's output format. Returns an error if unsuccessful.
+//
+// If the Parser has a Mapping then the columns of the Parser's ParsedData are
+// replaced by the mapped columns prior to writing. Otherwise, if the Parser is
+// configured to truncate headers then headers in the first row of the Parser's
+// ParsedData field are truncated prior to writing, and any duplicate headers are
+// handled by the Parser's DuplicateHeaderPolicy.
+//
+// Nothing is written if the ParsedData is empty.
+func (p *Parser) write(w io.Writer) error {
+	if len(p.ParsedData) == 0 {
+		return nil
+	}
+
+	switch {
+	case p.opts.mapping != nil:
+		mapped, err := p.opts.mapping.apply(p.ParsedData, objectSources(p.RootObj))
+		if err != nil {
+			return oops.Wrapf(err, "unable to apply mapping")
+		}
+		p.ParsedData = mapped
+	default:
+		// Shorten the header strings with the configured strategy.
+		if p.opts.truncateHeaders {
+			p.ParsedData[0] = p.opts.truncate(p.ParsedData[0])
+		}
+
+		header, err := p.opts.dedupe(p.ParsedData[0], objectSources(p.RootObj))
+		if err != nil {
+			return oops.Wrapf(err, "unable to write header row")
+		}
+		p.ParsedData[0] = header
+	}
+
+	// Write the Parser's ParsedData.
+	if err := writeDelimited(p.ParsedData, w, p.opts.comma()); err != nil {
+		return oops.Wrapf(err, "unable to write %s", p.opts.outputFormat)
+	}
 
 	return nil
 }
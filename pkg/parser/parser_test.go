@@ -1,7 +1,11 @@
 package parser_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -51,3 +55,376 @@ func TestConvertJSONFile(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertJSONFileRootValues(t *testing.T) {
+	testcases := []struct {
+		description string
+		infilePath  string
+		expected    [][]string
+	}{
+		{
+			description: "array of objects at the root",
+			infilePath:  "../testdata/jsontest_array.json",
+			expected: [][]string{
+				{"id", "name", "tags_color"},
+				{"1", "first", "red"},
+				{"2", "second", "blue"},
+			},
+		},
+		{
+			description: "array of scalars at the root",
+			infilePath:  "../testdata/jsontest_scalar_array.json",
+			expected: [][]string{
+				{"value"},
+				{"1"},
+				{"2.5"},
+				{"three"},
+				{"true"},
+			},
+		},
+		{
+			description: "ndjson records with different keys",
+			infilePath:  "../testdata/jsontest_lines.jsonl",
+			expected: [][]string{
+				{"event_at", "event_type", "id", "reason", "extra"},
+				{"1591056576414", "start", "1", "", ""},
+				{"1591056576500", "stop", "2", "done", ""},
+				{"", "", "3", "", "true"},
+			},
+		},
+		{
+			description: "scalar at the root",
+			infilePath:  "../testdata/jsontest_scalar.json",
+			expected: [][]string{
+				{"value"},
+				{"just a string"},
+			},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			outfile, err := parser.ConvertJSONFile(&testcase.infilePath, nil)
+			assert.NoError(t, err)
+			defer os.Remove(outfile.Name())
+
+			file, err := os.Open(outfile.Name())
+			assert.NoError(t, err)
+			defer file.Close()
+
+			actual, err := csv.NewReader(file).ReadAll()
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expected, actual)
+		})
+	}
+}
+
+// mustPattern returns the PathPattern for the given pattern, and panics if it's
+// malformed.
+func mustPattern(pattern string) *parser.PathPattern {
+	p, err := parser.ParsePathPattern(pattern)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func TestConvert(t *testing.T) {
+	testcases := []struct {
+		description string
+		input       string
+		opts        []parser.Option
+		expected    string
+		expectError bool
+	}{
+		{
+			description: "default options",
+			input:       `{"data": {"one": 1, "two": 2}}`,
+			expected:    "one,two\n1,2\n",
+		},
+		{
+			description: "without truncated headers",
+			input:       `{"data": {"one": 1, "two": 2}}`,
+			opts:        []parser.Option{parser.WithTruncateHeaders(false)},
+			expected:    "data_one,data_two\n1,2\n",
+		},
+		{
+			description: "ndjson input",
+			input:       "{\"one\": 1}\n{\"two\": 2}\n",
+			opts:        []parser.Option{parser.WithInputFormat(parser.FormatNDJSON)},
+			expected:    "one,two\n1,\n,2\n",
+		},
+		{
+			description: "streaming input",
+			input:       `[{"one": 1}, {"one": 2}]`,
+			opts:        []parser.Option{parser.WithStreaming(true)},
+			expected:    "one\n1\n2\n",
+		},
+		{
+			description: "later options override earlier ones",
+			input:       `{"data": {"one": 1, "two": 2}}`,
+			opts:        []parser.Option{parser.WithTruncateHeaders(false), parser.WithTruncateHeaders(true)},
+			expected:    "one,two\n1,2\n",
+		},
+		{
+			description: "tsv output with null token and separator",
+			input:       `{"data": {"one": null, "two": {"three": 3}}}`,
+			opts: []parser.Option{
+				parser.WithOutputFormat(parser.FormatTSV),
+				parser.WithNullToken(`\N`),
+				parser.WithHeaderSeparator("."),
+			},
+			expected: "one\ttwo.three\n\\N\t3\n",
+		},
+		{
+			description: "null token is different from an empty string",
+			input:       `[{"one": null, "two": ""}]`,
+			opts:        []parser.Option{parser.WithNullToken("NULL")},
+			expected:    "one,two\nNULL,\n",
+		},
+		{
+			description: "null token when streaming",
+			input:       `[{"one": null, "two": ""}]`,
+			opts:        []parser.Option{parser.WithNullToken(`\N`), parser.WithStreaming(true)},
+			expected:    "one,two\n\\N,\n",
+		},
+		{
+			description: "large numbers keep their precision",
+			input:       `[{"id": 4337769816123456789, "big": 123456789012345678901234567890}]`,
+			expected:    "big,id\n123456789012345678901234567890,4337769816123456789\n",
+		},
+		{
+			description: "large numbers keep their precision when streaming",
+			input:       `[{"id": 4337769816123456789}]`,
+			opts:        []parser.Option{parser.WithStreaming(true)},
+			expected:    "id\n4337769816123456789\n",
+		},
+		{
+			description: "scientific numbers",
+			input:       `{"one": 1500, "two": 0.25}`,
+			opts:        []parser.Option{parser.WithNumberFormat(parser.NumberScientific), parser.WithNumberPrecision(1)},
+			expected:    "one,two\n1.5e+03,2.5e-01\n",
+		},
+		{
+			description: "delimiter overrides output format",
+			input:       `{"one": 1, "two": 2}`,
+			opts:        []parser.Option{parser.WithOutputFormat(parser.FormatTSV), parser.WithDelimiter('|')},
+			expected:    "one|two\n1|2\n",
+		},
+		{
+			description: "escaped headers truncate on whole keys",
+			input:       `{"owner": {"user_id": 1, "user": {"id": 2}}}`,
+			opts:        []parser.Option{parser.WithHeaderStyle(parser.HeaderEscaped)},
+			expected:    "user_id,user\\_id\n2,1\n",
+		},
+		{
+			description: "pointer headers",
+			input:       `{"owner": {"user_id": 1, "a/b": 2}}`,
+			opts:        []parser.Option{parser.WithHeaderStyle(parser.HeaderPointer), parser.WithTruncateHeaders(false)},
+			expected:    "/owner/a~1b,/owner/user_id\n2,1\n",
+		},
+		{
+			description: "truncated pointer headers",
+			input:       `{"owner": {"user_id": 1, "a/b": 2}}`,
+			opts:        []parser.Option{parser.WithHeaderStyle(parser.HeaderPointer)},
+			expected:    "/a~1b,/user_id\n2,1\n",
+		},
+		{
+			description: "array elements with different keys",
+			input:       `[{"id": 1, "name": "a"}, {"id": 2, "email": "b"}]`,
+			expected:    "id,name,email\n1,a,\n2,,b\n",
+		},
+		{
+			description: "empty root array writes nothing",
+			input:       `[]`,
+			opts:        []parser.Option{parser.WithEmptyPolicy(parser.EmptyLiteral)},
+			expected:    "",
+		},
+		{
+			description: "empty array kept as a blank cell",
+			input:       `[{"id": 1, "tags": []}, {"id": 2, "tags": ["a"]}]`,
+			expected:    "id,tags\n1,\n2,a\n",
+		},
+		{
+			description: "empty array written as a literal",
+			input:       `[{"id": 1, "tags": []}, {"id": 2, "tags": ["a"]}]`,
+			opts:        []parser.Option{parser.WithEmptyPolicy(parser.EmptyLiteral)},
+			expected:    "id,tags\n1,[]\n2,a\n",
+		},
+		{
+			description: "empty object drops its row",
+			input:       `[{"id": 1, "meta": {}}, {"id": 2, "meta": {"a": 3}}]`,
+			opts:        []parser.Option{parser.WithEmptyPolicy(parser.EmptyDrop)},
+			expected:    "id,meta_a\n2,3\n",
+		},
+		{
+			description: "empty root object writes nothing",
+			input:       `{}`,
+			expected:    "",
+		},
+		{
+			description: "arrays as columns keep one row per record",
+			input:       `[{"id": 1, "events": [{"at": 10}, {"at": 20}]}, {"id": 2, "events": [{"at": 30}]}]`,
+			opts:        []parser.Option{parser.WithArrayMode(parser.ArrayColumns)},
+			expected:    "events_0_at,events_1_at,id\n10,20,1\n30,,2\n",
+		},
+		{
+			description: "arrays as columns when streaming",
+			input:       `[{"id": 1, "events": [{"at": 10}, {"at": 20}]}, {"id": 2, "events": [{"at": 30}]}]`,
+			opts:        []parser.Option{parser.WithArrayMode(parser.ArrayColumns), parser.WithStreaming(true)},
+			expected:    "events_0_at,events_1_at,id\n10,20,1\n30,,2\n",
+		},
+		{
+			description: "expect error for negative max array elements",
+			input:       `{"one": [1]}`,
+			opts:        []parser.Option{parser.WithArrayMode(parser.ArrayColumns), parser.WithMaxArrayElements(-1)},
+			expectError: true,
+		},
+		{
+			description: "scalar arrays joined into one cell",
+			input:       `[{"id": 1, "tags": ["a", "b"]}, {"id": 2, "tags": ["c"]}]`,
+			opts:        []parser.Option{parser.WithScalarArrayMode(parser.ScalarArrayJoin)},
+			expected:    "id,tags\n1,a|b\n2,c\n",
+		},
+		{
+			description: "scalar arrays as json",
+			input:       `{"id": 1, "tags": ["a", 2]}`,
+			opts:        []parser.Option{parser.WithScalarArrayMode(parser.ScalarArrayJSON)},
+			expected:    "id,tags\n1,\"[\"\"a\"\",2]\"\n",
+		},
+		{
+			description: "document key order",
+			input:       `{"id": 1, "name": "x", "address": {"zip": "1", "city": "y"}}`,
+			opts:        []parser.Option{parser.WithKeyOrder(parser.KeyOrderDocument)},
+			expected:    "id,name,address_zip,address_city\n1,x,1,y\n",
+		},
+		{
+			description: "document key order inside arrays",
+			input:       `[{"id": 1, "b": 2}, {"id": 3, "b": 4}]`,
+			opts:        []parser.Option{parser.WithKeyOrder(parser.KeyOrderDocument)},
+			expected:    "id,b\n1,2\n3,4\n",
+		},
+		{
+			description: "document key order for ndjson input",
+			input:       "{\"id\": 1, \"b\": 2}\n{\"c\": 3, \"id\": 4}\n",
+			opts: []parser.Option{
+				parser.WithInputFormat(parser.FormatNDJSON),
+				parser.WithKeyOrder(parser.KeyOrderDocument),
+			},
+			expected: "id,b,c\n1,2,\n4,,3\n",
+		},
+		{
+			description: "document key order when streaming",
+			input:       `[{"id": 1, "b": 2}, {"b": 4, "id": 3}]`,
+			opts:        []parser.Option{parser.WithStreaming(true), parser.WithKeyOrder(parser.KeyOrderDocument)},
+			expected:    "id,b\n1,2\n3,4\n",
+		},
+		{
+			description: "expect error for trailing data with document key order",
+			input:       `{"id": 1} {"id": 2}`,
+			opts:        []parser.Option{parser.WithKeyOrder(parser.KeyOrderDocument)},
+			expectError: true,
+		},
+		{
+			description: "expect error for invalid delimiter",
+			input:       `{"one": 1}`,
+			opts:        []parser.Option{parser.WithDelimiter('"')},
+			expectError: true,
+		},
+		{
+			description: "expect error for empty header separator",
+			input:       `{"one": 1}`,
+			opts:        []parser.Option{parser.WithHeaderSeparator("")},
+			expectError: true,
+		},
+		{
+			description: "zipped sibling arrays",
+			input:       `{"id": 7, "timestamps": [1, 2, 3], "values": [10, 20]}`,
+			opts:        []parser.Option{parser.WithSiblingMode(parser.SiblingZip), parser.WithZipPolicy(parser.ZipTruncate)},
+			expected:    "id,timestamps,values\n7,1,10\n7,2,20\n",
+		},
+		{
+			description: "expect error for zipped sibling arrays of different lengths",
+			input:       `{"id": 7, "timestamps": [1, 2, 3], "values": [10, 20]}`,
+			opts:        []parser.Option{parser.WithSiblingMode(parser.SiblingZip), parser.WithZipPolicy(parser.ZipError)},
+			expectError: true,
+		},
+		{
+			description: "excluded columns",
+			input:       `{"id": 1, "debug": {"trace": [1, 2, 3]}, "items": [{"v": 1, "raw": "x"}]}`,
+			opts:        []parser.Option{parser.WithExclude(mustPattern("debug"), mustPattern("*_raw"))},
+			expected:    "id,items_v\n1,1\n",
+		},
+		{
+			description: "every column excluded writes nothing",
+			input:       `{"id": 1}`,
+			opts:        []parser.Option{parser.WithInclude(mustPattern("name"))},
+			expected:    "",
+		},
+		{
+			description: "within output limits",
+			input:       `{"id": 1, "items": [{"v": 1}, {"v": 2}]}`,
+			opts:        []parser.Option{parser.WithMaxRows(2), parser.WithMaxColumns(2), parser.WithMaxCells(4)},
+			expected:    "id,items_v\n1,1\n1,2\n",
+		},
+		{
+			description: "expect error for too many rows",
+			input:       `{"id": 1, "items": [{"v": 1}, {"v": 2}]}`,
+			opts:        []parser.Option{parser.WithMaxRows(1)},
+			expectError: true,
+		},
+		{
+			description: "expect error for too many rows when streaming",
+			input:       `[{"id": 1}, {"id": 2}, {"id": 3}]`,
+			opts:        []parser.Option{parser.WithStreaming(true), parser.WithMaxRows(2)},
+			expectError: true,
+		},
+		{
+			description: "expect error for too many cells in ndjson input",
+			input:       "{\"one\": 1}\n{\"two\": 2}\n",
+			opts:        []parser.Option{parser.WithInputFormat(parser.FormatNDJSON), parser.WithMaxCells(3)},
+			expectError: true,
+		},
+		{
+			description: "expect error for negative output limits",
+			input:       `{"one": 1}`,
+			opts:        []parser.Option{parser.WithMaxColumns(-1)},
+			expectError: true,
+		},
+		{
+			description: "expect error for malformed input",
+			input:       `{"data": `,
+			expectError: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := parser.Convert(context.Background(), strings.NewReader(testcase.input), &buf, testcase.opts...)
+			assert.Equal(t, testcase.expectError, err != nil)
+			assert.Equal(t, testcase.expected, buf.String())
+		})
+	}
+}
+
+func TestConvertCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, opt := range []parser.Option{
+		parser.WithInputFormat(parser.FormatJSON),
+		parser.WithInputFormat(parser.FormatNDJSON),
+		parser.WithStreaming(true),
+	} {
+		var buf bytes.Buffer
+		err := parser.Convert(ctx, strings.NewReader("{\"one\": 1}\n"), &buf, opt)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), context.Canceled.Error())
+		}
+		assert.Empty(t, buf.String())
+	}
+
+	_, err := parser.ConvertTables(ctx, strings.NewReader(`{"one": 1}`))
+	assert.Error(t, err)
+}
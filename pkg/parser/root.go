@@ -0,0 +1,231 @@
+package parser
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/samsarahq/go/oops"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
+)
+
+// rootStep is one step of a root expression given with WithRoot. It selects
+// the child with the given token, a key of a map or an index of an array, or
+// every child if it's a wildcard.
+type rootStep struct {
+	token    string
+	wildcard bool
+}
+
+// parseRoot returns the steps of the given root expression, which is either a
+// JSON Pointer or a JSONPath. Returns an error if the expression is malformed.
+//
+// Only the subset of JSONPath that selects children is supported: `$`, `.key`,
+// `['key']`, `[0]`, and the wildcards `.*` and `[*]`.
+func parseRoot(expr string) ([]rootStep, error) {
+	if expr == "" || strings.HasPrefix(expr, "/") {
+		tokens, err := oo.SplitPointer(expr)
+		if err != nil {
+			return nil, oops.Wrapf(err, "invalid JSON Pointer: %s", expr)
+		}
+		steps := make([]rootStep, len(tokens))
+		for i, token := range tokens {
+			steps[i] = rootStep{token: token}
+		}
+		return steps, nil
+	}
+
+	if !strings.HasPrefix(expr, "$") {
+		return nil, oops.Errorf("root must be a JSON Pointer starting with '/' or a JSONPath starting with '$': %s", expr)
+	}
+
+	var steps []rootStep
+	rest := expr[1:]
+	for rest != "" {
+		var step rootStep
+		var err error
+		switch rest[0] {
+		case '.':
+			step, rest, err = parseDotStep(rest[1:])
+		case '[':
+			step, rest, err = parseBracketStep(rest[1:])
+		default:
+			err = oops.Errorf("unexpected %q", rest[0])
+		}
+		if err != nil {
+			return nil, oops.Wrapf(err, "invalid JSONPath: %s", expr)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+// parseDotStep returns the step for a `.key` or `.*` at the start of the given
+// JSONPath, after the dot, and the rest of the JSONPath.
+func parseDotStep(path string) (rootStep, string, error) {
+	end := strings.IndexAny(path, ".[")
+	if end == -1 {
+		end = len(path)
+	}
+
+	name := path[:end]
+	switch name {
+	case "":
+		return rootStep{}, "", oops.Errorf("missing key after '.', recursive descent isn't supported")
+	case "*":
+		return rootStep{wildcard: true}, path[end:], nil
+	}
+	return rootStep{token: name}, path[end:], nil
+}
+
+// parseBracketStep returns the step for a `['key']`, `[0]` or `[*]` at the
+// start of the given JSONPath, after the opening bracket, and the rest of the
+// JSONPath.
+func parseBracketStep(path string) (rootStep, string, error) {
+	if path != "" && (path[0] == '\'' || path[0] == '"') {
+		end := strings.Index(path[1:], string(path[0])+"]")
+		if end == -1 {
+			return rootStep{}, "", oops.Errorf("unterminated key: [%s", path)
+		}
+		return rootStep{token: path[1 : end+1]}, path[end+3:], nil
+	}
+
+	end := strings.Index(path, "]")
+	if end == -1 {
+		return rootStep{}, "", oops.Errorf("missing ']': [%s", path)
+	}
+
+	inner := path[:end]
+	if inner == "*" {
+		return rootStep{wildcard: true}, path[end+1:], nil
+	}
+	if _, err := strconv.Atoi(inner); err != nil {
+		return rootStep{}, "", oops.Errorf("expected a quoted key, an index or '*': [%s]", inner)
+	}
+	return rootStep{token: inner}, path[end+1:], nil
+}
+
+// hasWildcard returns true if any of the given steps is a wildcard, so the
+// steps can select more than one value.
+func hasWildcard(steps []rootStep) bool {
+	for _, step := range steps {
+		if step.wildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// selectRoot returns the values selected by the given steps, starting from the
+// given value. Children that don't exist are skipped, so the result is empty if
+// nothing matches.
+func selectRoot(value interface{}, steps []rootStep) []interface{} {
+	matches := []interface{}{value}
+	for _, step := range steps {
+		var next []interface{}
+		for _, m := range matches {
+			next = append(next, selectChildren(m, step)...)
+		}
+		matches = next
+	}
+	return matches
+}
+
+// selectChildren returns the children of the given value selected by the given
+// step. The children of a map are in the order of its keys, sorted unless the
+// map is an OrderedMap.
+func selectChildren(value interface{}, step rootStep) []interface{} {
+	switch vv := value.(type) {
+	case map[string]interface{}:
+		if !step.wildcard {
+			if child, ok := vv[step.token]; ok {
+				return []interface{}{child}
+			}
+			return nil
+		}
+		keys := make([]string, 0, len(vv))
+		for k := range vv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		children := make([]interface{}, len(keys))
+		for i, k := range keys {
+			children[i] = vv[k]
+		}
+		return children
+	case *oo.OrderedMap:
+		if !step.wildcard {
+			if child, ok := vv.Values[step.token]; ok {
+				return []interface{}{child}
+			}
+			return nil
+		}
+		children := make([]interface{}, len(vv.Keys))
+		for i, k := range vv.Keys {
+			children[i] = vv.Values[k]
+		}
+		return children
+	case []interface{}:
+		if step.wildcard {
+			return vv
+		}
+		index, err := strconv.Atoi(step.token)
+		if err != nil || index < 0 || index >= len(vv) {
+			return nil
+		}
+		return []interface{}{vv[index]}
+	}
+	return nil
+}
+
+// applyRoot returns the part of the given input selected by the Parser's root
+// expression. The input is the value read from the document, or a slice of the
+// values of each line for NDJSON input.
+//
+// If the expression can only match a single value, that value replaces the
+// document. Otherwise the matches, or the matches from every line of NDJSON
+// input, are collected as records in a slice, with the elements of any array
+// match concatenated as records of their own. Returns an error if nothing
+// matches.
+func (p *Parser) applyRoot(raw interface{}) (interface{}, error) {
+	if p.opts.root == "" {
+		return raw, nil
+	}
+
+	steps, err := parseRoot(p.opts.root)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []interface{}
+	if lines, ok := raw.([]interface{}); ok && p.opts.inputFormat == FormatNDJSON {
+		for _, line := range lines {
+			records = appendRecords(records, selectRoot(line, steps))
+		}
+	} else {
+		matches := selectRoot(raw, steps)
+		if len(matches) == 1 && !hasWildcard(steps) {
+			return matches[0], nil
+		}
+		records = appendRecords(records, matches)
+	}
+
+	if len(records) == 0 {
+		return nil, oops.Errorf("root %s doesn't match anything in the input", p.opts.root)
+	}
+	return records, nil
+}
+
+// appendRecords appends the given matches to the records. A match that is an
+// array holds records of its own, so its elements are appended in its place.
+func appendRecords(records []interface{}, matches []interface{}) []interface{} {
+	for _, match := range matches {
+		if arr, ok := match.([]interface{}); ok {
+			records = append(records, arr...)
+			continue
+		}
+		records = append(records, match)
+	}
+	return records
+}
@@ -0,0 +1,178 @@
+package parser_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ecshreve/jcgo/pkg/parser"
+)
+
+func TestRoot(t *testing.T) {
+	envelope := `{
+		"data": {"items": [{"id": 1, "name": "a"}, {"id": 2, "name": "b"}]},
+		"pages": [{"items": [{"id": 3}]}, {"items": [{"id": 4}, {"id": 5}]}],
+		"meta": {"first/page": {"n": 1}, "count": 2}
+	}`
+
+	testcases := []struct {
+		description string
+		input       string
+		root        string
+		opts        []parser.Option
+		expected    string
+		expectError bool
+	}{
+		{
+			description: "json pointer",
+			input:       envelope,
+			root:        "/data/items",
+			expected:    "id,name\n1,a\n2,b\n",
+		},
+		{
+			description: "json pointer to an array element",
+			input:       envelope,
+			root:        "/data/items/1",
+			expected:    "id,name\n2,b\n",
+		},
+		{
+			description: "json pointer with escaped key",
+			input:       envelope,
+			root:        "/meta/first~1page",
+			expected:    "n\n1\n",
+		},
+		{
+			description: "jsonpath",
+			input:       envelope,
+			root:        "$.data.items",
+			expected:    "id,name\n1,a\n2,b\n",
+		},
+		{
+			description: "jsonpath with brackets",
+			input:       envelope,
+			root:        "$['meta']['first/page']",
+			expected:    "n\n1\n",
+		},
+		{
+			description: "jsonpath with index",
+			input:       envelope,
+			root:        "$.pages[1].items[0]",
+			expected:    "id\n4\n",
+		},
+		{
+			description: "jsonpath wildcard matches are records",
+			input:       envelope,
+			root:        "$.pages[*].items[*]",
+			expected:    "id\n3\n4\n5\n",
+		},
+		{
+			description: "jsonpath wildcard matching arrays concatenates their elements",
+			input:       envelope,
+			root:        "$.pages[*].items",
+			expected:    "id\n3\n4\n5\n",
+		},
+		{
+			description: "jsonpath wildcard matching arrays with array columns",
+			input:       `{"pages": [{"items": [{"id": 1}, {"id": 2}]}, {"items": [{"id": 3}]}]}`,
+			root:        "$.pages[*].items",
+			opts:        []parser.Option{parser.WithArrayMode(parser.ArrayColumns)},
+			expected:    "id\n1\n2\n3\n",
+		},
+		{
+			description: "jsonpath wildcard over a map",
+			input:       envelope,
+			root:        "$.meta.*",
+			opts:        []parser.Option{parser.WithKeyOrder(parser.KeyOrderDocument)},
+			expected:    "n,value\n1,\n,2\n",
+		},
+		{
+			description: "whole document",
+			input:       `{"a": 1}`,
+			root:        "$",
+			expected:    "a\n1\n",
+		},
+		{
+			description: "ndjson lines",
+			input:       "{\"data\": {\"id\": 1}}\n{\"other\": 2}\n{\"data\": {\"id\": 3}}\n",
+			root:        "/data",
+			opts:        []parser.Option{parser.WithInputFormat(parser.FormatNDJSON)},
+			expected:    "id\n1\n3\n",
+		},
+		{
+			description: "ndjson lines matching arrays",
+			input:       "{\"data\": [{\"id\": 1}, {\"id\": 2}]}\n{\"data\": [{\"id\": 3}]}\n",
+			root:        "/data",
+			opts: []parser.Option{
+				parser.WithInputFormat(parser.FormatNDJSON),
+				parser.WithArrayMode(parser.ArrayColumns),
+			},
+			expected: "id\n1\n2\n3\n",
+		},
+		{
+			description: "expect error if nothing matches",
+			input:       envelope,
+			root:        "/data/missing",
+			expectError: true,
+		},
+		{
+			description: "expect error if wildcard matches nothing",
+			input:       envelope,
+			root:        "$.meta.count[*]",
+			expectError: true,
+		},
+		{
+			description: "expect error for a path that's neither",
+			input:       envelope,
+			root:        "data.items",
+			expectError: true,
+		},
+		{
+			description: "expect error for recursive descent",
+			input:       envelope,
+			root:        "$..items",
+			expectError: true,
+		},
+		{
+			description: "expect error for unterminated bracket",
+			input:       envelope,
+			root:        "$['data'",
+			expectError: true,
+		},
+		{
+			description: "expect error when streaming",
+			input:       envelope,
+			root:        "/data/items",
+			opts:        []parser.Option{parser.WithStreaming(true)},
+			expectError: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			opts := append([]parser.Option{parser.WithRoot(testcase.root)}, testcase.opts...)
+
+			var buf bytes.Buffer
+			err := parser.Convert(context.Background(), strings.NewReader(testcase.input), &buf, opts...)
+			if testcase.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expected, buf.String())
+		})
+	}
+}
+
+func TestRootTables(t *testing.T) {
+	input := `{"data": {"items": [{"id": 1, "tags": ["a", "b"]}]}, "meta": {"count": 1}}`
+
+	tables, err := parser.ConvertTables(context.Background(), strings.NewReader(input), parser.WithRoot("/data/items"))
+	assert.NoError(t, err)
+	assert.Equal(t, []parser.Table{
+		{Name: "", Data: [][]string{{"_row_id", "id"}, {"1", "1"}}, Sources: []string{"", "$[*].id"}},
+		{Name: "tags", Data: [][]string{{"_row_id", "_parent_row_id", "_ordinal", "value"}, {"1", "1", "0", "a"}, {"2", "1", "1", "b"}}, Sources: []string{"", "", "", "$[*].tags[*]"}},
+	}, tables)
+}
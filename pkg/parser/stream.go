@@ -0,0 +1,363 @@
+package parser
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/samsarahq/go/oops"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
+)
+
+// recordStream reads the records of a JSON input one at a time, so only a
+// single record needs to be held in memory.
+//
+// For a JSON document the records are the elements of the array at the
+// selected path, or the value at the selected path itself if it's not an
+// array. For NDJSON input every line is a record, read the same way as when it
+// isn't streamed.
+//
+// Once the records of a JSON document are read, the rest of the input is read
+// to the end, so the input must be valid JSON with nothing after the top-level
+// value, the same as when it isn't streamed.
+type recordStream struct {
+	dec     *json.Decoder
+	lines   *jsonLines
+	ordered bool
+	inArray bool
+	depth   int
+	single  interface{}
+	done    bool
+}
+
+// newRecordStream returns a recordStream reading from the given io.Reader in
+// the input format of the given options. The records are selected with the
+// stream path of the options, a JSON Pointer to the value holding the records,
+// and an empty path selects the root of the document. Returns an error if the
+// path can't be found in the input.
+func newRecordStream(r io.Reader, opts options) (*recordStream, error) {
+	path := opts.streamPath
+	s := &recordStream{
+		ordered: opts.object.KeyOrder == oo.KeyOrderDocument,
+	}
+
+	if opts.inputFormat == FormatNDJSON {
+		if path != "" {
+			return nil, oops.Errorf("a stream path can't be used with ndjson input: %s", path)
+		}
+		s.lines = newJSONLines(r, s.ordered)
+		return s, nil
+	}
+	s.dec = newDecoder(r)
+
+	segments, err := oo.SplitPointer(path)
+	if err != nil {
+		return nil, oops.Wrapf(err, "invalid stream path: %s", path)
+	}
+
+	tok, err := s.seek(segments)
+	if err != nil {
+		return nil, oops.Wrapf(err, "unable to find stream path: %s", path)
+	}
+	s.depth = len(segments)
+
+	// An array at the path holds the records, anything else is a single record.
+	if tok == json.Delim('[') {
+		s.inArray = true
+		return s, nil
+	}
+
+	s.single, err = readValue(s.dec, tok, s.ordered)
+	if err != nil {
+		return nil, oops.Wrapf(err, "unable to read value at stream path: %s", path)
+	}
+	if err := s.finish(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Next returns the next record in the recordStream, or io.EOF once all the
+// records have been read.
+func (s *recordStream) Next() (interface{}, error) {
+	if s.done {
+		return nil, io.EOF
+	}
+
+	if s.lines != nil {
+		record, err := s.lines.Next()
+		if err == io.EOF {
+			s.done = true
+		}
+		return record, err
+	}
+
+	// A single record is returned once.
+	if !s.inArray {
+		s.done = true
+		return s.single, nil
+	}
+
+	if !s.dec.More() {
+		s.done = true
+		if err := s.finish(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+
+	record, err := decodeValue(s.dec, s.ordered)
+	if err != nil {
+		return nil, oops.Wrapf(err, "unable to decode record at offset %d", s.dec.InputOffset())
+	}
+	return record, nil
+}
+
+// finish reads the rest of the input after the last record: the end of the
+// array holding the records, and the rest of every value on the stream path.
+// Returns an error if the input isn't valid JSON, or if there's anything after
+// the top-level value.
+func (s *recordStream) finish() error {
+	depth := s.depth
+	if s.inArray {
+		depth++
+	}
+
+	for depth > 0 {
+		tok, err := s.dec.Token()
+		if err != nil {
+			return oops.Wrapf(err, "unable to read the rest of the input")
+		}
+		switch tok {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+	}
+
+	// Like json.Unmarshal, only accept a single value.
+	if _, err := s.dec.Token(); err != io.EOF {
+		return oops.Errorf("invalid data after top-level value at offset %d", s.dec.InputOffset())
+	}
+	return nil
+}
+
+// seek advances the recordStream's decoder to the value at the given path, and
+// returns the first token of that value. Values that aren't on the path are
+// skipped without being decoded.
+func (s *recordStream) seek(path []string) (json.Token, error) {
+	tok, err := s.dec.Token()
+	if err != nil {
+		return nil, oops.Wrapf(err, "unable to read token")
+	}
+
+	for _, segment := range path {
+		switch tok {
+		case json.Delim('{'):
+			if err := s.seekKey(segment); err != nil {
+				return nil, err
+			}
+		case json.Delim('['):
+			if err := s.seekIndex(segment); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, oops.Errorf("can't select %q from scalar value: %v", segment, tok)
+		}
+
+		if tok, err = s.dec.Token(); err != nil {
+			return nil, oops.Wrapf(err, "unable to read token")
+		}
+	}
+
+	return tok, nil
+}
+
+// seekKey advances the recordStream's decoder, which must be inside an object,
+// to the value for the given key.
+func (s *recordStream) seekKey(key string) error {
+	for s.dec.More() {
+		tok, err := s.dec.Token()
+		if err != nil {
+			return oops.Wrapf(err, "unable to read key")
+		}
+		if tok == key {
+			return nil
+		}
+		if err := skipValue(s.dec); err != nil {
+			return oops.Wrapf(err, "unable to skip value for key: %v", tok)
+		}
+	}
+	return oops.Errorf("key not found: %s", key)
+}
+
+// seekIndex advances the recordStream's decoder, which must be inside an array,
+// to the element at the given index.
+func (s *recordStream) seekIndex(segment string) error {
+	index, err := strconv.Atoi(segment)
+	if err != nil || index < 0 {
+		return oops.Errorf("invalid array index: %s", segment)
+	}
+
+	for i := 0; s.dec.More(); i++ {
+		if i == index {
+			return nil
+		}
+		if err := skipValue(s.dec); err != nil {
+			return oops.Wrapf(err, "unable to skip array element: %d", i)
+		}
+	}
+	return oops.Errorf("array index out of range: %d", index)
+}
+
+// writeRecords writes the rows parsed from each record in the given
+// recordStream as CSV to the given io.Writer. Returns an error if unsuccessful,
+// or if the context is cancelled before all the records are written.
+func writeRecords(ctx context.Context, records *recordStream, out io.Writer, opts options) error {
+	w := &streamWriter{
+		writer: csv.NewWriter(out),
+		opts:   opts,
+	}
+	w.writer.Comma = opts.comma()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return oops.Wrapf(err, "writing records cancelled")
+		}
+
+		record, err := records.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return oops.Wrapf(err, "unable to read record")
+		}
+
+		if err := w.writeRecord(record); err != nil {
+			return oops.Wrapf(err, "unable to write record")
+		}
+	}
+
+	w.writer.Flush()
+	if err := w.writer.Error(); err != nil {
+		return oops.Wrapf(err, "unable to flush csv writer")
+	}
+	return nil
+}
+
+// streamWriter writes the rows parsed from each record of a recordStream to a
+// csv.Writer as soon as the record is parsed.
+//
+// The header row is taken from the first record, and the rows from every later
+// record are aligned to it by column name. A column name that's repeated in the
+// header row maps to the output column of each repeat, in order.
+type streamWriter struct {
+	writer  *csv.Writer
+	opts    options
+	header  map[string][]int
+	width   int
+	records int
+	rows    int
+}
+
+// writeRecord parses the given record and writes its rows. Returns an error if
+// the record has a column that isn't in the header row.
+func (w *streamWriter) writeRecord(record interface{}) error {
+	obj, err := w.opts.object.FromInterface("", record)
+	if err != nil {
+		return oops.Wrapf(err, "unable to build Object for record %d", w.records)
+	}
+
+	if err := w.opts.object.CheckLimits(obj); err != nil {
+		return oops.Wrapf(err, "record %d is too large", w.records)
+	}
+
+	parsed, err := obj.Parse()
+	if err != nil {
+		return oops.Wrapf(err, "unable to parse record %d", w.records)
+	}
+	w.records++
+
+	// A record without any columns can't define the header row, and has no
+	// cells to write.
+	if len(parsed) == 0 || len(parsed[0]) == 0 {
+		return nil
+	}
+	fillRootHeaders(parsed[0])
+
+	// With a Mapping every record has the same mapped columns. The required
+	// columns must be in the first record, which defines the header row. A
+	// later record without one of them leaves it empty, the same as its rows
+	// would be when the input isn't streamed.
+	if w.opts.mapping != nil {
+		if w.header == nil {
+			parsed, err = w.opts.mapping.apply(parsed, objectSources(obj))
+		} else {
+			var columns []int
+			if columns, _, err = w.opts.mapping.columns(parsed[0], objectSources(obj)); err == nil {
+				parsed = w.opts.mapping.rows(parsed, columns)
+			}
+		}
+		if err != nil {
+			return oops.Wrapf(err, "unable to apply mapping to record %d", w.records)
+		}
+	}
+
+	// The first record defines the header row.
+	if w.header == nil {
+		w.header = make(map[string][]int)
+		for i, h := range parsed[0] {
+			w.header[h] = append(w.header[h], i)
+		}
+		w.width = len(parsed[0])
+
+		header := append([]string(nil), parsed[0]...)
+		if w.opts.mapping == nil {
+			if w.opts.truncateHeaders {
+				header = w.opts.truncate(header)
+			}
+			if header, err = w.opts.dedupe(header, objectSources(obj)); err != nil {
+				return oops.Wrapf(err, "unable to write header row")
+			}
+		}
+		if err := w.writer.Write(header); err != nil {
+			return oops.Wrapf(err, "unable to write header row")
+		}
+	}
+
+	// The limits apply to the whole output, not just to each record.
+	w.rows += len(parsed) - 1
+	if max := w.opts.object.MaxRows; max > 0 && w.rows > max {
+		return oops.Errorf("row limit of %d exceeded at record %d", max, w.records)
+	}
+	if max := w.opts.object.MaxCells; max > 0 && w.rows*w.width > max {
+		return oops.Errorf("cell limit of %d exceeded at record %d", max, w.records)
+	}
+
+	// Find the output column for each of the record's columns.
+	columns := make([]int, len(parsed[0]))
+	seen := make(map[string]int)
+	for i, h := range parsed[0] {
+		if seen[h] >= len(w.header[h]) {
+			return oops.Errorf("record %d has column %q that isn't in the header from the first record", w.records, h)
+		}
+		columns[i] = w.header[h][seen[h]]
+		seen[h]++
+	}
+
+	for _, row := range parsed[1:] {
+		aligned := make([]string, w.width)
+		for i, cell := range row {
+			aligned[columns[i]] = cell
+		}
+		if err := w.writer.Write(aligned); err != nil {
+			return oops.Wrapf(err, "unable to write row for record %d", w.records)
+		}
+	}
+
+	return nil
+}
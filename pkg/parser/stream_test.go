@@ -0,0 +1,274 @@
+package parser_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ecshreve/jcgo/pkg/parser"
+)
+
+func TestStream(t *testing.T) {
+	testcases := []struct {
+		description string
+		infilePath  string
+		format      parser.InputFormat
+		streamPath  string
+		expected    [][]string
+		expectError bool
+	}{
+		{
+			description: "array of objects at the root",
+			infilePath:  "../testdata/jsontest_array.json",
+			expected: [][]string{
+				{"id", "name", "tags_color"},
+				{"1", "first", "red"},
+				{"2", "second", "blue"},
+			},
+		},
+		{
+			description: "array selected by path",
+			infilePath:  "../testdata/jsontest_envelope.json",
+			streamPath:  "/data/items",
+			expected: [][]string{
+				{"id", "name", "tags"},
+				{"1", "first", "a"},
+				{"1", "first", "b"},
+				{"2", "second", "c"},
+				{"3", "third", "d"},
+			},
+		},
+		{
+			description: "array element selected by path",
+			infilePath:  "../testdata/jsontest_envelope.json",
+			streamPath:  "/data/items/1",
+			expected: [][]string{
+				{"id", "name", "tags"},
+				{"2", "second", "c"},
+			},
+		},
+		{
+			description: "object at the root is a single record",
+			infilePath:  "../testdata/jsontest_envelope.json",
+			streamPath:  "/meta",
+			expected: [][]string{
+				{"count", "pages"},
+				{"3", "1"},
+				{"3", "2"},
+				{"3", "3"},
+			},
+		},
+		{
+			description: "expect error if path doesn't exist",
+			infilePath:  "../testdata/jsontest_envelope.json",
+			streamPath:  "/data/nonexistent",
+			expectError: true,
+		},
+		{
+			description: "expect error if path isn't a JSON Pointer",
+			infilePath:  "../testdata/jsontest_envelope.json",
+			streamPath:  "data",
+			expectError: true,
+		},
+		{
+			description: "expect error if a later record has a new column",
+			infilePath:  "../testdata/jsontest_lines.jsonl",
+			format:      parser.FormatNDJSON,
+			expectError: true,
+		},
+		{
+			description: "expect error if infile is malformed",
+			infilePath:  "../testdata/jsontest_bad.json",
+			expectError: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			infile, err := os.Open(testcase.infilePath)
+			assert.NoError(t, err)
+			defer infile.Close()
+
+			var buf bytes.Buffer
+			err = parser.Convert(context.Background(), infile, &buf,
+				parser.WithInputFormat(testcase.format),
+				parser.WithStreaming(true),
+				parser.WithStreamPath(testcase.streamPath),
+			)
+			if testcase.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			actual, err := csv.NewReader(&buf).ReadAll()
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expected, actual)
+		})
+	}
+}
+
+func TestStreamRestOfInput(t *testing.T) {
+	testcases := []struct {
+		description string
+		input       string
+		format      parser.InputFormat
+		streamPath  string
+		expected    string
+		expectError bool
+	}{
+		{
+			description: "whitespace after the array",
+			input:       "[{\"a\": 1}]\n\n",
+			expected:    "a\n1\n",
+		},
+		{
+			description: "values after the stream path",
+			input:       `{"data": [{"a": 1}], "meta": {"pages": [1, 2]}, "count": 1}`,
+			streamPath:  "/data",
+			expected:    "a\n1\n",
+		},
+		{
+			description: "expect error for data after the array",
+			input:       `[{"a": 1}] garbage`,
+			expectError: true,
+		},
+		{
+			description: "expect error for a second value after the array",
+			input:       `[{"a": 1}] [{"a": 2}]`,
+			expectError: true,
+		},
+		{
+			description: "expect error for data after the document",
+			input:       `{"data": [{"a": 1}]} garbage`,
+			streamPath:  "/data",
+			expectError: true,
+		},
+		{
+			description: "expect error for malformed values after the stream path",
+			input:       `{"data": [{"a": 1}], "meta": }`,
+			streamPath:  "/data",
+			expectError: true,
+		},
+		{
+			description: "expect error for data after a single record",
+			input:       `{"data": {"a": 1}} garbage`,
+			streamPath:  "/data",
+			expectError: true,
+		},
+		{
+			description: "empty array writes nothing",
+			input:       `[]`,
+			expected:    "",
+		},
+		{
+			description: "empty ndjson writes nothing",
+			input:       "\n\n",
+			format:      parser.FormatNDJSON,
+			expected:    "",
+		},
+		{
+			description: "expect error for two values on one ndjson line",
+			input:       "{\"a\": 1} {\"a\": 2}\n",
+			format:      parser.FormatNDJSON,
+			expectError: true,
+		},
+		{
+			description: "expect error for a stray delimiter in ndjson",
+			input:       "{\"a\": 1}\n]\n",
+			format:      parser.FormatNDJSON,
+			expectError: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := parser.Convert(context.Background(), strings.NewReader(testcase.input), &buf,
+				parser.WithInputFormat(testcase.format),
+				parser.WithStreaming(true),
+				parser.WithStreamPath(testcase.streamPath),
+			)
+			if testcase.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, testcase.expected, buf.String())
+		})
+	}
+}
+
+func TestStreamMatchesBuffered(t *testing.T) {
+	testcases := []struct {
+		description string
+		input       string
+		format      parser.InputFormat
+		expectError string
+	}{
+		{
+			description: "array of records",
+			input:       `[{"a": 1}, {"a": 2}]`,
+		},
+		{
+			description: "empty array",
+			input:       `[]`,
+		},
+		{
+			description: "record without columns",
+			input:       `[{}]`,
+		},
+		{
+			description: "ndjson",
+			input:       "{\"a\": 1}\n\n{\"a\": 2}\n",
+			format:      parser.FormatNDJSON,
+		},
+		{
+			description: "empty ndjson",
+			input:       "",
+			format:      parser.FormatNDJSON,
+		},
+		{
+			description: "expect error naming the line with two values",
+			input:       "{\"a\": 1}\n{\"a\": 2} {\"a\": 3}\n",
+			format:      parser.FormatNDJSON,
+			expectError: "line 2",
+		},
+		{
+			description: "expect error naming the malformed line",
+			input:       "{\"a\": 1}\n{\"a\": }\n",
+			format:      parser.FormatNDJSON,
+			expectError: "line 2",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			var outputs []string
+			for _, stream := range []bool{false, true} {
+				var buf bytes.Buffer
+				err := parser.Convert(context.Background(), strings.NewReader(testcase.input), &buf,
+					parser.WithInputFormat(testcase.format),
+					parser.WithStreaming(stream),
+				)
+				if testcase.expectError != "" {
+					if assert.Error(t, err) {
+						assert.Contains(t, err.Error(), testcase.expectError)
+					}
+					continue
+				}
+				assert.NoError(t, err)
+				outputs = append(outputs, buf.String())
+			}
+
+			if testcase.expectError == "" {
+				assert.Equal(t, outputs[0], outputs[1])
+			}
+		})
+	}
+}
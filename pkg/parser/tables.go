@@ -0,0 +1,200 @@
+package parser
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/samsarahq/go/oops"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
+)
+
+// Table is one of the linked tables produced by ConvertTables. The Name is the
+// column header of the array the rows come from, and is empty for the root
+// table. The first row of the Data holds the column headers, and the Sources
+// hold the JSONPath of the values in each column.
+type Table = oo.Table
+
+// The generated columns that link the Tables produced by ConvertTables. Every
+// table has a RowIDColumn numbering its rows from 1. The rows of a child table
+// also have a ParentRowIDColumn with the RowIDColumn of the row holding their
+// array in the parent table, and an OrdinalColumn with their index in that
+// array.
+const (
+	RowIDColumn       = oo.RowIDColumn
+	ParentRowIDColumn = oo.ParentRowIDColumn
+	OrdinalColumn     = oo.OrdinalColumn
+)
+
+// RootTableName is the file name, without an extension, of the root table
+// written by WriteTables.
+const RootTableName = "root"
+
+// ConvertTables reads JSON from the given io.Reader and normalizes it into
+// linked tables, configured with the given Options. Returns an error if the
+// conversion was unsuccessful, or if the context is cancelled before it's done.
+//
+// Instead of exploding nested arrays into repeated rows, the elements of each
+// array path become the rows of their own table, which can be joined back to
+// the parent table on the generated key columns. The root table has a row for
+// the root value, or a row for each record if the root is an array or the
+// input is NDJSON.
+func ConvertTables(ctx context.Context, r io.Reader, opts ...Option) ([]Table, error) {
+	return NewParser(opts...).ConvertTables(ctx, r)
+}
+
+// ConvertTables reads JSON from the given io.Reader and normalizes it into
+// linked tables, see the ConvertTables function. The tables are also stored in
+// the Parser's Tables field.
+//
+// Headers are never truncated, since the headers of each table are already
+// relative to its array, and streaming isn't supported. Duplicate headers
+// within a table are handled by the Parser's DuplicateHeaderPolicy.
+func (p *Parser) ConvertTables(ctx context.Context, r io.Reader) ([]Table, error) {
+	if err := p.opts.validate(); err != nil {
+		return nil, oops.Wrapf(err, "invalid options")
+	}
+	if p.opts.stream {
+		return nil, oops.Errorf("normalized tables can't be streamed")
+	}
+	if p.opts.mapping != nil {
+		return nil, oops.Errorf("normalized tables can't be mapped")
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, oops.Wrapf(err, "converting cancelled")
+	}
+
+	if err := p.read(r); err != nil {
+		return nil, oops.Wrapf(err, "unable to read input")
+	}
+
+	if err := p.buildRootObj(); err != nil {
+		return nil, oops.Wrapf(err, "unable to build root object for value: %v", p.Raw)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, oops.Wrapf(err, "normalizing cancelled")
+	}
+
+	tables, err := p.opts.object.Normalize(p.RootObj)
+	if err != nil {
+		return nil, oops.Wrapf(err, "unable to normalize root object: %v", p.RootObj)
+	}
+
+	for i, table := range tables {
+		header, err := p.opts.dedupe(table.Data[0], func() ([]string, error) {
+			return table.Sources, nil
+		})
+		if err != nil {
+			return nil, oops.Wrapf(err, "unable to write header row of table: %q", table.Name)
+		}
+		tables[i].Data[0] = header
+	}
+
+	p.Tables = tables
+	return tables, nil
+}
+
+// ConvertJSONFileTables converts a JSON file at the given path to a file for
+// each of its normalized tables in the given directory, and returns the paths
+// of the files written, or an error if unsuccessful.
+//
+// The input path can be StdioPath to read from stdin. If no output directory is
+// given then a default one is generated. The input format is detected the same
+// way as for ConvertJSONFile.
+func ConvertJSONFileTables(infilePath string, outDir *string, opts ...Option) ([]string, error) {
+	pp, err := newFileParser(infilePath, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	infile, err := openInfile(infilePath)
+	if err != nil {
+		return nil, oops.Wrapf(err, "unable to open file %s", infilePath)
+	}
+	defer infile.Close()
+
+	tables, err := pp.ConvertTables(context.Background(), infile)
+	if err != nil {
+		return nil, oops.Wrapf(err, "unable to convert file: %s", infilePath)
+	}
+
+	dir := GetDefaultOutdirPath()
+	if outDir != nil {
+		dir = *outDir
+	}
+
+	paths, err := WriteTables(tables, dir, opts...)
+	if err != nil {
+		return nil, oops.Wrapf(err, "unable to write tables to directory: %s", dir)
+	}
+	return paths, nil
+}
+
+// WriteTables writes each of the given Tables to its own file in the given
+// directory, in the output format of the given Options, and returns the paths
+// of the files written. The directory is created if it doesn't exist. Returns
+// an error if unsuccessful, or if two tables would be written to the same file.
+//
+// The file names are built with TableFileName.
+func WriteTables(tables []Table, dir string, opts ...Option) ([]string, error) {
+	o := newOptions(opts...)
+	if err := o.validate(); err != nil {
+		return nil, oops.Wrapf(err, "invalid options")
+	}
+
+	// Check every file name before writing anything.
+	var paths []string
+	seen := make(map[string]string)
+	for _, table := range tables {
+		name := TableFileName(table.Name)
+		if other, ok := seen[name]; ok {
+			return nil, oops.Errorf("tables %q and %q would both be written to %s", other, table.Name, name)
+		}
+		seen[name] = table.Name
+		paths = append(paths, filepath.Join(dir, name+o.outputFormat.Extension()))
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, oops.Wrapf(err, "unable to create directory: %s", dir)
+	}
+
+	for i, table := range tables {
+		if err := writeTableFile(table, paths[i], o.comma()); err != nil {
+			return nil, oops.Wrapf(err, "unable to write table: %s", table.Name)
+		}
+	}
+
+	return paths, nil
+}
+
+// writeTableFile writes the given Table to a new file at the given path with
+// the given field delimiter.
+func writeTableFile(table Table, path string, comma rune) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return oops.Wrapf(err, "unable to create file: %s", path)
+	}
+	defer file.Close()
+
+	if err := writeDelimited(table.Data, file, comma); err != nil {
+		return oops.Wrapf(err, "unable to write file: %s", path)
+	}
+	return nil
+}
+
+// TableFileName returns the file name, without an extension, for the Table
+// with the given name. The root table is RootTableName, and the separators of
+// JSON Pointer headers are replaced with dots, i.e. `/events/tags` becomes
+// `events.tags`.
+func TableFileName(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if name == "" || strings.HasPrefix(name, oo.NestedArraySuffix) {
+		name = RootTableName + name
+	}
+	return strings.NewReplacer("/", ".", `\`, ".").Replace(name)
+}
@@ -0,0 +1,136 @@
+package parser_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ecshreve/jcgo/pkg/parser"
+)
+
+func TestConvertTables(t *testing.T) {
+	testcases := []struct {
+		description string
+		input       string
+		opts        []parser.Option
+		expected    []parser.Table
+		expectError bool
+	}{
+		{
+			description: "nested arrays become linked tables",
+			input:       `{"id": 1, "events": [{"type": "start"}, {"type": "stop"}], "afterState": [{"on": true}]}`,
+			opts:        []parser.Option{parser.WithKeyOrder(parser.KeyOrderDocument)},
+			expected: []parser.Table{
+				{Name: "", Data: [][]string{{"_row_id", "id"}, {"1", "1"}}, Sources: []string{"", "$.id"}},
+				{Name: "events", Data: [][]string{{"_row_id", "_parent_row_id", "_ordinal", "type"}, {"1", "1", "0", "start"}, {"2", "1", "1", "stop"}}, Sources: []string{"", "", "", "$.events[*].type"}},
+				{Name: "afterState", Data: [][]string{{"_row_id", "_parent_row_id", "_ordinal", "on"}, {"1", "1", "0", "true"}}, Sources: []string{"", "", "", "$.afterState[*].on"}},
+			},
+		},
+		{
+			description: "ndjson records are rows of the root table",
+			input:       "{\"id\": 1, \"tags\": [\"a\"]}\n{\"id\": 2, \"tags\": [\"b\", \"c\"]}\n",
+			opts:        []parser.Option{parser.WithInputFormat(parser.FormatNDJSON)},
+			expected: []parser.Table{
+				{Name: "", Data: [][]string{{"_row_id", "id"}, {"1", "1"}, {"2", "2"}}, Sources: []string{"", "$[*].id"}},
+				{Name: "tags", Data: [][]string{{"_row_id", "_parent_row_id", "_ordinal", "value"}, {"1", "1", "0", "a"}, {"2", "2", "0", "b"}, {"3", "2", "1", "c"}}, Sources: []string{"", "", "", "$[*].tags[*]"}},
+			},
+		},
+		{
+			description: "duplicate headers in a table",
+			input:       `{"a_b": 1, "a": {"b": 2}}`,
+			opts:        []parser.Option{parser.WithDuplicateHeaders(parser.DuplicatePath)},
+			expected: []parser.Table{
+				{Name: "", Data: [][]string{{"_row_id", "$.a.b", "$.a_b"}, {"1", "2", "1"}}, Sources: []string{"", "$.a.b", "$.a_b"}},
+			},
+		},
+		{
+			description: "expect error for duplicate headers in a table",
+			input:       `{"a_b": 1, "a": {"b": 2}}`,
+			expectError: true,
+		},
+		{
+			description: "expect error for arrays at different paths with the same table name",
+			input:       `{"a": [{"b": [1]}], "a_b": [2]}`,
+			expectError: true,
+		},
+		{
+			description: "expect error when streaming",
+			input:       `[{"id": 1}]`,
+			opts:        []parser.Option{parser.WithStreaming(true)},
+			expectError: true,
+		},
+		{
+			description: "expect error for malformed input",
+			input:       `[{"id": 1}`,
+			expectError: true,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			tables, err := parser.ConvertTables(context.Background(), strings.NewReader(testcase.input), testcase.opts...)
+			assert.Equal(t, testcase.expectError, err != nil)
+			assert.Equal(t, testcase.expected, tables)
+		})
+	}
+}
+
+func TestConvertJSONFileTables(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jcgo")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	outDir := filepath.Join(dir, "tables")
+	paths, err := parser.ConvertJSONFileTables("../testdata/jsontest_envelope.json", &outDir, parser.WithOutputFormat(parser.FormatTSV))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(outDir, "root.tsv"),
+		filepath.Join(outDir, "data_items.tsv"),
+		filepath.Join(outDir, "data_items_tags.tsv"),
+		filepath.Join(outDir, "meta_pages.tsv"),
+	}, paths)
+
+	actual, err := ioutil.ReadFile(filepath.Join(outDir, "data_items_tags.tsv"))
+	assert.NoError(t, err)
+	assert.Equal(t, "_row_id\t_parent_row_id\t_ordinal\tvalue\n1\t1\t0\ta\n2\t1\t1\tb\n3\t2\t0\tc\n4\t3\t0\td\n", string(actual))
+}
+
+func TestWriteTablesNameCollision(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jcgo")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tables := []parser.Table{
+		{Name: "/a/b", Data: [][]string{{"_row_id"}}},
+		{Name: "a.b", Data: [][]string{{"_row_id"}}},
+	}
+	_, err = parser.WriteTables(tables, dir)
+	assert.Error(t, err)
+
+	// Nothing is written if any of the names collide.
+	files, err := ioutil.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestTableFileName(t *testing.T) {
+	testcases := []struct {
+		name     string
+		expected string
+	}{
+		{name: "", expected: "root"},
+		{name: "[]", expected: "root[]"},
+		{name: "events", expected: "events"},
+		{name: "events_tags[]", expected: "events_tags[]"},
+		{name: "/events/tags", expected: "events.tags"},
+	}
+
+	for _, testcase := range testcases {
+		assert.Equal(t, testcase.expected, parser.TableFileName(testcase.name))
+	}
+}
@@ -0,0 +1,100 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/samsarahq/go/oops"
+
+	oo "github.com/ecshreve/jcgo/internal/object"
+)
+
+// TruncateStrategy describes how column headers are shortened before writing.
+type TruncateStrategy int
+
+const (
+	// TruncateCommonPrefix removes the longest common prefix among all of the
+	// headers, see TruncateColumnHeaders.
+	TruncateCommonPrefix TruncateStrategy = iota
+	// TruncateUniqueSuffix reduces each header to the shortest suffix of its
+	// keys that no other header ends with, see UniqueSuffixHeaders.
+	TruncateUniqueSuffix
+)
+
+// String returns the name of the TruncateStrategy, as accepted by
+// ParseTruncateStrategy.
+func (s TruncateStrategy) String() string {
+	switch s {
+	case TruncateCommonPrefix:
+		return "prefix"
+	case TruncateUniqueSuffix:
+		return "suffix"
+	default:
+		return "unknown"
+	}
+}
+
+// ParseTruncateStrategy returns the TruncateStrategy with the given name, or an
+// error if the name doesn't match any TruncateStrategy.
+func ParseTruncateStrategy(name string) (TruncateStrategy, error) {
+	for _, s := range []TruncateStrategy{TruncateCommonPrefix, TruncateUniqueSuffix} {
+		if s.String() == name {
+			return s, nil
+		}
+	}
+	return TruncateCommonPrefix, oops.Errorf("unknown truncate strategy: %s", name)
+}
+
+// truncate returns the given headers shortened with the options' strategy.
+func (o options) truncate(headers []string) []string {
+	if o.truncateStrategy == TruncateUniqueSuffix {
+		return uniqueSuffixHeaders(headers, &o.object)
+	}
+	return truncateColumnHeaders(headers, &o.object)
+}
+
+// UniqueSuffixHeaders returns a slice of strings with each of the given headers
+// reduced to the shortest suffix of its keys that is still unique, so
+// `afterState_destinationName` becomes `destinationName` unless another header
+// also ends with that key, in which case it keeps as many keys as it takes to
+// tell them apart, like `afterState_id` and `beforeState_id`.
+//
+// Headers are only ever shortened to whole keys. Distinct headers always give
+// distinct results, a header that is repeated in the input is left as it is.
+func UniqueSuffixHeaders(headers []string) []string {
+	return uniqueSuffixHeaders(headers, oo.DefaultConfig())
+}
+
+// uniqueSuffixHeaders returns the given headers reduced to their shortest
+// unique suffixes, splitting and joining them with the given Config.
+func uniqueSuffixHeaders(headers []string, cfg *oo.Config) []string {
+	split := make([][]string, len(headers))
+	for i, header := range headers {
+		split[i] = cfg.SplitHeader(header)
+	}
+
+	// Count the headers ending with each suffix of keys. A suffix is keyed by
+	// its keys joined with a character that can't be in a header.
+	counts := make(map[string]int)
+	for _, tokens := range split {
+		for k := 1; k <= len(tokens); k++ {
+			counts[suffixKey(tokens, k)]++
+		}
+	}
+
+	ret := make([]string, len(headers))
+	for i, tokens := range split {
+		ret[i] = headers[i]
+		for k := 1; k < len(tokens); k++ {
+			if counts[suffixKey(tokens, k)] == 1 {
+				ret[i] = cfg.JoinHeader(tokens[len(tokens)-k:])
+				break
+			}
+		}
+	}
+	return ret
+}
+
+// suffixKey returns a key identifying the last k of the given tokens.
+func suffixKey(tokens []string, k int) string {
+	return strings.Join(tokens[len(tokens)-k:], "\x00")
+}
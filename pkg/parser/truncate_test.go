@@ -0,0 +1,112 @@
+package parser_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/ecshreve/jcgo/pkg/parser"
+)
+
+func TestUniqueSuffixHeaders(t *testing.T) {
+	testcases := []struct {
+		description string
+		input       []string
+		expected    []string
+	}{
+		{
+			description: "unambiguous keys",
+			input:       []string{"afterState_destinationName", "afterState_jobState", "changedAtMs"},
+			expected:    []string{"destinationName", "jobState", "changedAtMs"},
+		},
+		{
+			description: "ambiguous keys keep their parents",
+			input:       []string{"afterState_id", "afterState_jobState", "beforeState_id", "id"},
+			expected:    []string{"afterState_id", "jobState", "beforeState_id", "id"},
+		},
+		{
+			description: "no shared root",
+			input:       []string{"a_x_name", "b_y_name", "c_name_first"},
+			expected:    []string{"x_name", "y_name", "first"},
+		},
+		{
+			description: "deeper ambiguity",
+			input:       []string{"a_x_id", "b_x_id", "c_id"},
+			expected:    []string{"a_x_id", "b_x_id", "c_id"},
+		},
+		{
+			description: "repeated headers are left as they are",
+			input:       []string{"a_id", "a_id", "b_name"},
+			expected:    []string{"a_id", "a_id", "name"},
+		},
+		{
+			description: "single header",
+			input:       []string{"data_id"},
+			expected:    []string{"id"},
+		},
+		{
+			description: "empty",
+			input:       []string{},
+			expected:    []string{},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.description, func(t *testing.T) {
+			assert.Equal(t, testcase.expected, parser.UniqueSuffixHeaders(testcase.input))
+		})
+	}
+}
+
+func TestUniqueSuffixHeadersAreUnique(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	keys := []string{"a", "b", "id", "name"}
+
+	for i := 0; i < 500; i++ {
+		seen := make(map[string]bool)
+		var headers []string
+		for j := 0; j < 1+r.Intn(8); j++ {
+			tokens := make([]string, 1+r.Intn(4))
+			for k := range tokens {
+				tokens[k] = keys[r.Intn(len(keys))]
+			}
+			header := strings.Join(tokens, "_")
+			if !seen[header] {
+				seen[header] = true
+				headers = append(headers, header)
+			}
+		}
+
+		actual := parser.UniqueSuffixHeaders(headers)
+		unique := make(map[string]bool)
+		for j, h := range actual {
+			assert.False(t, unique[h], "duplicate header %q from %v", h, headers)
+			assert.True(t, strings.HasSuffix(headers[j], h), fmt.Sprintf("%q isn't a suffix of %q", h, headers[j]))
+			unique[h] = true
+		}
+	}
+}
+
+func TestConvertUniqueSuffix(t *testing.T) {
+	input := `{"afterState": {"id": 1, "name": "a"}, "beforeState": {"id": 2, "name": "b"}, "changedAtMs": 3}`
+
+	var buf bytes.Buffer
+	err := parser.Convert(context.Background(), strings.NewReader(input), &buf,
+		parser.WithTruncateStrategy(parser.TruncateUniqueSuffix),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "afterState_id,afterState_name,beforeState_id,beforeState_name,changedAtMs\n1,a,2,b,3\n", buf.String())
+
+	buf.Reset()
+	err = parser.Convert(context.Background(), strings.NewReader(`[{"data": {"user": {"id": 1}, "id": 2}}]`), &buf,
+		parser.WithTruncateStrategy(parser.TruncateUniqueSuffix),
+		parser.WithStreaming(true),
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "data_id,user_id\n2,1\n", buf.String())
+}